@@ -1,14 +1,48 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/rmnvlv/golang-cinema-api/internal/config"
-	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler"
-	_ "github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/auth"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/actors"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/apikeys"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/cast"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/health"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/loglevel"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/movies"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/search"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/session"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/users"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/version"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger/handler/pretty"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger/handler/sampling"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/metrics"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/migrate"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/postgres"
 	"github.com/rmnvlv/golang-cinema-api/internal/storage/sqlite"
+	"github.com/rmnvlv/golang-cinema-api/internal/token"
+	"github.com/rmnvlv/golang-cinema-api/internal/tracing"
 )
 
 const (
@@ -17,54 +51,345 @@ const (
 	envProd  = "prod"
 )
 
+const (
+	logFormatText   = "text"
+	logFormatJSON   = "json"
+	logFormatPretty = "pretty"
+
+	logOutputStdout = "stdout"
+	logOutputFile   = "file"
+)
+
+const (
+	storageSQLite   = "sqlite"
+	storagePostgres = "postgres"
+	storageMemory   = "memory"
+)
+
 func main() {
 
 	//init config: cleanenv
 	cfg := config.MustLoad()
 
 	//init logger: slog
-	log := initLogger(cfg.Env)
+	log, logLevel, err := initLogger(cfg.Env, cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed with init logger: %v\n", err)
+		os.Exit(1)
+	}
 	log.Info("Loger init completed", slog.String("env", cfg.Env))
 
-	//init storage: sqlite
-	storage, err := sqlite.New(cfg.StoragePath)
+	//subcommand: migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Error("migrate failed", slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	//init storage: sqlite, postgres or memory
+	storage, err := initStorage(cfg)
 	if err != nil {
-		log.Error("failed with init storage", err)
+		log.Error("failed with init storage", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("Storage init complited", slog.String("storage", cfg.Storage), slog.String("path", cfg.StoragePath))
+
+	if err := ensureAdmin(context.Background(), cfg.Auth, storage); err != nil {
+		log.Error("failed to create admin account", slog.Any("error", err))
 		os.Exit(1)
 	}
-	log.Info("Storage init complited", slog.String("storage", cfg.StoragePath))
 
-	_ = storage
+	//readiness: database reachable, schema current, not shutting down
+	var shuttingDown atomic.Bool
+	var checks []health.Check
+	if migrated, ok := storage.(interface{ Migrator() *migrate.Migrator }); ok {
+		checks = append(checks, health.Check{Name: "migrations", Run: health.Migrations(migrated.Migrator())})
+	}
 
-	//init router
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", handler.New(log, storage))
+	//init metrics: prometheus
+	m := metrics.New()
+	if pool, ok := storage.(interface{ DB() *sql.DB }); ok {
+		if err := m.RegisterDB(cfg.Storage, pool.DB()); err != nil {
+			log.Error("failed with init metrics", slog.Any("error", err))
+			os.Exit(1)
+		}
+		checks = append(checks, health.Check{Name: "database", Run: pool.DB().PingContext})
+	}
+	storage = m.InstrumentStorage(cfg.Storage, storage)
+
+	//init tracing: opentelemetry
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Error("failed with init tracing", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	//init router: chi
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(logger.New(log, requestLogger(log, cfg.Log.Sampling)))
+	router.Use(m.Middleware)
+	router.NotFound(response.NotFound)
+	router.MethodNotAllowed(response.MethodNotAllowed)
+
+	router.Handle("/metrics", m.Handler())
+	router.Get("/healthz", health.Live)
+	router.Get("/readyz", health.Ready(log, &shuttingDown, checks...))
+	router.Get("/version", version.Get)
+
+	//auth: tokens are optional, accounts are not
+	var tokens auth.AccessVerifier
+	if len(cfg.JWT.Keys) > 0 {
+		manager, err := token.New(cfg.JWT)
+		if err != nil {
+			log.Error("failed with init jwt", slog.Any("error", err))
+			os.Exit(1)
+		}
+		tokens = manager
+
+		router.Route("/auth", func(r chi.Router) {
+			r.Post("/login", session.Login(log, storage, manager))
+			r.Post("/refresh", session.Refresh(log, storage, manager))
+		})
+	}
+
+	//every route needs an account or an API key, writes need a scope for them
+	router.Group(func(router chi.Router) {
+		router.Use(auth.New(log, storage, tokens))
+		read := auth.Require(log, models.ScopeMoviesRead)
+		moviesWrite := auth.Require(log, models.ScopeMoviesWrite)
+		actorsWrite := auth.Require(log, models.ScopeActorsWrite)
+
+		router.Route("/movies", func(r chi.Router) {
+			r.With(read).Get("/", movies.List(log, storage))
+			r.With(read).Get("/{id}", movies.Get(log, storage))
+			r.With(moviesWrite).Post("/", movies.Create(log, storage))
+			r.With(moviesWrite).Patch("/{id}", movies.Update(log, storage))
+			r.With(moviesWrite).Delete("/{id}", movies.Delete(log, storage))
+			r.With(moviesWrite).Put("/{id}/actors", cast.Set(log, storage))
+			r.With(moviesWrite).Post("/{id}/actors", cast.Add(log, storage))
+			r.With(moviesWrite).Delete("/{id}/actors", cast.Delete(log, storage))
+		})
+
+		router.Route("/actors", func(r chi.Router) {
+			r.With(read).Get("/", actors.List(log, storage))
+			r.With(read).Get("/{id}", actors.Get(log, storage))
+			r.With(actorsWrite).Post("/", actors.Create(log, storage))
+			r.With(actorsWrite).Patch("/{id}", actors.Update(log, storage))
+			r.With(actorsWrite).Delete("/{id}", actors.Delete(log, storage))
+		})
+
+		router.With(read).Get("/search", search.Search(log, storage))
+
+		router.Route("/admin", func(r chi.Router) {
+			r.Use(auth.RequireRole(log, models.RoleAdmin))
+			r.Post("/users", users.Create(log, storage))
+			r.Post("/api-keys", apikeys.Create(log, storage))
+			r.Get("/api-keys", apikeys.List(log, storage))
+			r.Delete("/api-keys/{id}", apikeys.Revoke(log, storage))
+			r.Get("/log-level", loglevel.Get(logLevel))
+			r.Put("/log-level", loglevel.Set(log, logLevel))
+		})
+	})
+
+	srv := &http.Server{
+		Addr:         cfg.Address,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdeleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Debug("Initializing server...", slog.String("Server: ", cfg.Address))
 	//run server
-	http.ListenAndServe(cfg.Address, mux)
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Info("starting server", slog.String("address", cfg.Address))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Error("server failed", slog.Any("error", err))
+		closeStorage(log, storage)
+		os.Exit(1)
+	case <-ctx.Done():
+		stop()
+	}
+	shuttingDown.Store(true)
+
+	//graceful shutdown: fail readiness, stop accepting, drain in-flight requests, close storage
+	if delay := cfg.HTTPServer.ShutdownDelay; delay > 0 {
+		log.Info("draining before shutdown", slog.Duration("delay", delay))
+		time.Sleep(delay)
+	}
+	log.Info("shutting down server", slog.Duration("timeout", cfg.HTTPServer.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("graceful shutdown failed, closing connections", slog.Any("error", err))
+		srv.Close()
+	}
+	closeStorage(log, storage)
+
+	//flush traces with a deadline of their own: draining may have used up shutdownCtx
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), tracing.ShutdownTimeout)
+	defer cancelTracing()
+
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Error("failed to flush traces", slog.Any("error", err))
+	}
+
+	log.Info("server stopped")
+}
+
+// requestLogger is the logger for "request completed" records: log, sampled
+// as cfg says if sampling is on.
+func requestLogger(log *slog.Logger, cfg config.LogSampling) *slog.Logger {
+	if cfg.First <= 0 {
+		return log
+	}
+
+	return slog.New(sampling.New(log.Handler(), sampling.Options{
+		First:      cfg.First,
+		Thereafter: cfg.Thereafter,
+		Tick:       cfg.Tick,
+	}))
+}
+
+// closeStorage releases the database handle of backends that hold one.
+func closeStorage(log *slog.Logger, s storage.Repository) {
+	closer, ok := s.(io.Closer)
+	if !ok {
+		return
+	}
+
+	if err := closer.Close(); err != nil {
+		log.Error("failed to close storage", slog.Any("error", err))
+	}
+}
+
+func initStorage(cfg config.Config) (storage.Repository, error) {
+	switch cfg.Storage {
+	case storageSQLite:
+		if cfg.StoragePath == "" {
+			return nil, fmt.Errorf("storage_path is required for %s storage", storageSQLite)
+		}
+		s, err := sqlite.New(cfg.StoragePath)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case storagePostgres:
+		if cfg.StorageDSN == "" {
+			return nil, fmt.Errorf("storage_dsn is required for %s storage", storagePostgres)
+		}
+		s, err := postgres.New(cfg.StorageDSN)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case storageMemory:
+		return memory.New(), nil
+	}
+
+	return nil, fmt.Errorf("unknown storage: %q", cfg.Storage)
 }
 
-func initLogger(env string) *slog.Logger {
-	var log *slog.Logger
+// ensureAdmin creates the admin account named in the config unless it
+// already exists, so that a fresh database can be managed at all.
+func ensureAdmin(ctx context.Context, cfg config.Auth, s storage.Repository) error {
+	if cfg.AdminUsername == "" || cfg.AdminPassword == "" {
+		return nil
+	}
+
+	_, err := s.GetUser(ctx, cfg.AdminUsername)
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return err
+	}
+
+	hash, err := auth.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.CreateUser(ctx, cfg.AdminUsername, hash, models.RoleAdmin)
+	return err
+}
+
+// initLogger builds the logger cfg describes. Level and format default by
+// env. The returned LevelVar is the logger's level and may be changed while
+// it runs.
+func initLogger(env string, cfg config.Log) (*slog.Logger, *slog.LevelVar, error) {
+	level := new(slog.LevelVar)
+	var format string
 
 	switch env {
 	case envLocal:
-		log = slog.New(
-			slog.NewTextHandler(
-				os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
+		level.Set(slog.LevelDebug)
+		format = logFormatPretty
 	case envDev:
-		log = slog.New(
-			slog.NewJSONHandler(
-				os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
+		level.Set(slog.LevelDebug)
+		format = logFormatJSON
 	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(
-				os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		level.Set(slog.LevelInfo)
+		format = logFormatJSON
+	default:
+		return nil, nil, fmt.Errorf("unknown env: %q", env)
+	}
+
+	if cfg.Level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, nil, fmt.Errorf("log level: %w", err)
+		}
+		level.Set(l)
+	}
+	if cfg.Format != "" {
+		format = cfg.Format
+	}
+
+	var out io.Writer
+	switch cfg.Output {
+	case logOutputStdout:
+		out = os.Stdout
+	case logOutputFile:
+		if cfg.File.Path == "" {
+			return nil, nil, fmt.Errorf("log file path is required for %s output", logOutputFile)
+		}
+		out = &lumberjack.Logger{
+			Filename:   cfg.File.Path,
+			MaxSize:    cfg.File.MaxSizeMB,
+			MaxAge:     cfg.File.MaxAgeDays,
+			MaxBackups: cfg.File.MaxBackups,
+			Compress:   cfg.File.Compress,
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown log output: %q", cfg.Output)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case logFormatText:
+		handler = slog.NewTextHandler(out, opts)
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(out, opts)
+	case logFormatPretty:
+		handler = pretty.PrettyHandlerOptions{SlogOpts: opts}.NewPrettyHandler(out)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format: %q", format)
 	}
 
-	return log
+	return slog.New(handler), level, nil
 }
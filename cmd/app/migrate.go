@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/config"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/migrate"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/postgres"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/sqlite"
+)
+
+const migrateUsage = "usage: app migrate up|down|status"
+
+// runMigrate implements the "migrate up|down|status" subcommand.
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, closer, err := openMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		if err := migrator.Down(); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return printMigrationStatus(migrator)
+}
+
+func openMigrator(cfg config.Config) (*migrate.Migrator, io.Closer, error) {
+	switch cfg.Storage {
+	case storageSQLite:
+		s, err := sqlite.Open(cfg.StoragePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s.Migrator(), s, nil
+	case storagePostgres:
+		s, err := postgres.Open(cfg.StorageDSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s.Migrator(), s, nil
+	}
+
+	return nil, nil, fmt.Errorf("storage %q has no migrations", cfg.Storage)
+}
+
+func printMigrationStatus(migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		switch {
+		case status.Applied:
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		case status.Skipped:
+			appliedAt = "skipped"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
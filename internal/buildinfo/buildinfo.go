@@ -0,0 +1,75 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version, Commit and BuildTime are set at link time:
+//
+//	go build -ldflags "\
+//	  -X github.com/rmnvlv/golang-cinema-api/internal/buildinfo.Version=v1.4.0 \
+//	  -X github.com/rmnvlv/golang-cinema-api/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/rmnvlv/golang-cinema-api/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+//	  ./cmd/app
+//
+// Left empty, Version and Commit fall back to what the Go toolchain
+// embedded in the binary. The toolchain records when the commit was made,
+// not when the binary was built, so that is reported as CommitTime and
+// BuildTime stays unknown.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+const unknown = "unknown"
+
+// Info describes the running binary.
+type Info struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	CommitTime string `json:"commit_time"`
+	BuildTime  string `json:"build_time"`
+	GoVersion  string `json:"go_version"`
+}
+
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		var revision, revisionTime string
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.time":
+				revisionTime = setting.Value
+			}
+		}
+		if info.Commit == "" {
+			info.Commit = revision
+		}
+		// A commit given at link time may not be the one checked out.
+		if revision != "" && info.Commit == revision {
+			info.CommitTime = revisionTime
+		}
+	}
+
+	for _, field := range []*string{&info.Version, &info.Commit, &info.CommitTime, &info.BuildTime} {
+		if *field == "" {
+			*field = unknown
+		}
+	}
+
+	return info
+}
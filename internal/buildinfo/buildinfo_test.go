@@ -0,0 +1,64 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// linkTime sets the link-time variables for the rest of the test.
+func linkTime(t *testing.T, version, commit, buildTime string) {
+	t.Helper()
+
+	oldVersion, oldCommit, oldBuildTime := Version, Commit, BuildTime
+	t.Cleanup(func() { Version, Commit, BuildTime = oldVersion, oldCommit, oldBuildTime })
+
+	Version, Commit, BuildTime = version, commit, buildTime
+}
+
+// revision is the commit the toolchain embedded, if it did.
+func revision() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range bi.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+
+	return ""
+}
+
+func TestGetLinkTime(t *testing.T) {
+	linkTime(t, "v1.4.0", "0123abc", "2026-10-01T12:00:00Z")
+
+	info := Get()
+	if info.Version != "v1.4.0" || info.Commit != "0123abc" || info.BuildTime != "2026-10-01T12:00:00Z" {
+		t.Errorf("Get() = %+v, want the link-time values", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+	}
+	if revision() != "0123abc" && info.CommitTime != unknown {
+		t.Errorf("CommitTime = %q for a commit the binary wasn't built from", info.CommitTime)
+	}
+}
+
+func TestGetFallback(t *testing.T) {
+	linkTime(t, "", "", "")
+
+	info := Get()
+	if info.BuildTime != unknown {
+		t.Errorf("BuildTime = %q, want %q", info.BuildTime, unknown)
+	}
+	if want := revision(); want != "" && info.Commit != want {
+		t.Errorf("Commit = %q, want the embedded %q", info.Commit, want)
+	}
+	for name, value := range map[string]string{"Version": info.Version, "Commit": info.Commit, "CommitTime": info.CommitTime} {
+		if value == "" {
+			t.Errorf("%s is empty, want a value or %q", name, unknown)
+		}
+	}
+}
@@ -9,15 +9,101 @@ import (
 )
 
 type Config struct {
-	Env         string `yaml:"env" env-default:"local"`
-	StoragePath string `yaml:"storage_path" env-required:"local"`
+	Env string `yaml:"env" env-default:"local"`
+	// Storage selects the backend: "sqlite", "postgres" or "memory".
+	Storage     string `yaml:"storage" env-default:"sqlite"`
+	StoragePath string `yaml:"storage_path"`
+	StorageDSN  string `yaml:"storage_dsn" env:"STORAGE_DSN"`
 	HTTPServer  `yaml:"http_server"`
+	Auth        `yaml:"auth"`
+	JWT         `yaml:"jwt"`
+	Log         `yaml:"log"`
+	Tracing     `yaml:"tracing"`
 }
 
+// HTTPServer configures the listener. Timeout bounds reading a request and
+// writing its response; ShutdownTimeout is how long in-flight requests get
+// to finish once the process is asked to stop. ShutdownDelay is how long the
+// server keeps serving, with /readyz failing, before it stops accepting
+// connections, so that a load balancer has time to take it out of rotation.
 type HTTPServer struct {
-	Address      string        `yaml:"adderss" env-default:"localhost:8080"`
-	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
-	IdeleTimeout time.Duration `yaml:"idle_timeout" env-default:"120s"`
+	Address         string        `yaml:"adderss" env-default:"localhost:8080"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"10s"`
+	IdeleTimeout    time.Duration `yaml:"idle_timeout" env-default:"120s"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env-default:"0s"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+}
+
+// Auth names an admin account created at startup if it doesn't exist yet.
+// Leave the password out of the file and set ADMIN_PASSWORD instead.
+type Auth struct {
+	AdminUsername string `yaml:"admin_username" env:"ADMIN_USERNAME"`
+	AdminPassword string `yaml:"admin_password" env:"ADMIN_PASSWORD"`
+}
+
+// JWT configures the tokens POST /auth/login issues. Tokens are signed with
+// the key whose ID is SigningKey and verified with whichever key their kid
+// header names, so a retired key can stay listed until its tokens expire.
+// Without keys, login is disabled.
+type JWT struct {
+	Issuer     string        `yaml:"issuer" env-default:"golang-cinema-api"`
+	AccessTTL  time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	SigningKey string        `yaml:"signing_key" env:"JWT_SIGNING_KEY"`
+	Keys       []JWTKey      `yaml:"keys"`
+}
+
+// JWTKey is one signing key. Alg is HS256, which takes Secret, or EdDSA,
+// which takes a base64 Ed25519 PrivateKey (a 32 byte seed or the full 64
+// bytes) or, for a key only kept to verify old tokens, a PublicKey.
+type JWTKey struct {
+	ID         string `yaml:"id"`
+	Alg        string `yaml:"alg"`
+	Secret     string `yaml:"secret"`
+	PrivateKey string `yaml:"private_key"`
+	PublicKey  string `yaml:"public_key"`
+}
+
+// Log configures the application logger. Level (debug, info, warn, error)
+// and Format (text, json, pretty) default by Env; Output is stdout or
+// file, which writes to File.Path and rotates it.
+type Log struct {
+	Level    string      `yaml:"level" env:"LOG_LEVEL"`
+	Format   string      `yaml:"format" env:"LOG_FORMAT"`
+	Output   string      `yaml:"output" env:"LOG_OUTPUT" env-default:"stdout"`
+	File     LogFile     `yaml:"file"`
+	Sampling LogSampling `yaml:"sampling"`
+}
+
+// LogFile rotates the log once it grows past MaxSizeMB, keeping at most
+// MaxBackups old files (0 keeps all) for at most MaxAgeDays (0 keeps them
+// forever).
+type LogFile struct {
+	Path       string `yaml:"path" env:"LOG_FILE"`
+	MaxSizeMB  int    `yaml:"max_size_mb" env-default:"100"`
+	MaxAgeDays int    `yaml:"max_age_days" env-default:"7"`
+	MaxBackups int    `yaml:"max_backups" env-default:"5"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// LogSampling thins out the "request completed" logs: per Tick, the First
+// records are kept, then every Thereafter-th. Other records, including those
+// handlers log for a request, are never sampled. First 0 turns it off.
+type LogSampling struct {
+	First      int           `yaml:"first"`
+	Thereafter int           `yaml:"thereafter"`
+	Tick       time.Duration `yaml:"tick" env-default:"1s"`
+}
+
+// Tracing configures OpenTelemetry. Exporter is none, which still passes
+// incoming trace context on to the logs; stdout, which prints finished spans;
+// or otlp, which sends them over OTLP/HTTP to Endpoint, a URL such as
+// http://localhost:4318. SampleRatio applies to traces started here.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" env-default:"http://localhost:4318"`
+	ServiceName string  `yaml:"service_name" env-default:"golang-cinema-api"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
 }
 
 func MustLoad() Config {
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+)
+
+// storeKey creates a key with scopes, expiring at expiresAt if given, and
+// returns its plaintext.
+func storeKey(t *testing.T, store *memory.Storage, scopes []models.Scope, expiresAt *time.Time) string {
+	t.Helper()
+
+	plaintext, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateAPIKey(context.Background(), models.APIKey{
+		Name:      "importer",
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return plaintext
+}
+
+func TestNewAPIKey(t *testing.T) {
+	format := regexp.MustCompile(`^cin_([0-9a-f]{12})_[A-Za-z0-9_-]{43}$`)
+
+	plaintext, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := format.FindStringSubmatch(plaintext)
+	if m == nil {
+		t.Fatalf("key %q doesn't look like cin_<prefix>_<secret>", plaintext)
+	}
+	if m[1] != prefix {
+		t.Errorf("prefix = %q, key has %q", prefix, m[1])
+	}
+	if hash != hashAPIKey(plaintext) || strings.Contains(hash, plaintext) {
+		t.Errorf("hash = %q, not the hash of the key", hash)
+	}
+
+	other, otherPrefix, _, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == plaintext || otherPrefix == prefix {
+		t.Errorf("two keys share %q", prefix)
+	}
+}
+
+// failingStore fails every lookup, as a broken database would.
+type failingStore struct{}
+
+var errDown = errors.New("database is down")
+
+func (failingStore) GetAPIKey(ctx context.Context, prefix string) (models.APIKey, error) {
+	return models.APIKey{}, errDown
+}
+
+func (failingStore) TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) error {
+	return errDown
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tomorrow := now.Add(24 * time.Hour)
+	live := storeKey(t, store, []models.Scope{models.ScopeMoviesRead}, &tomorrow)
+	forever := storeKey(t, store, []models.Scope{models.ScopeMoviesRead}, nil)
+	expired := storeKey(t, store, []models.Scope{models.ScopeMoviesRead}, &now)
+
+	for _, plaintext := range []string{live, forever} {
+		key, err := AuthenticateAPIKey(ctx, store, plaintext, now)
+		if err != nil {
+			t.Errorf("AuthenticateAPIKey: %v", err)
+		}
+		if !strings.HasPrefix(plaintext, "cin_"+key.Prefix+"_") {
+			t.Errorf("got key %q for %q", key.Prefix, plaintext)
+		}
+	}
+
+	parts := strings.SplitN(live, "_", 3)
+	unknown, _, _, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		plaintext string
+		now       time.Time
+	}{
+		{"expired", expired, now},
+		{"expired since", live, tomorrow},
+		{"wrong secret", parts[0] + "_" + parts[1] + "_" + strings.Repeat("A", len(parts[2])), now},
+		{"truncated secret", live[:len(live)-1], now},
+		{"unknown prefix", unknown, now},
+		{"wrong scheme", "key_" + parts[1] + "_" + parts[2], now},
+		{"no secret", parts[0] + "_" + parts[1], now},
+		{"empty", "", now},
+	}
+	for _, tt := range tests {
+		if _, err := AuthenticateAPIKey(ctx, store, tt.plaintext, tt.now); !errors.Is(err, response.ErrUnauthorized) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, response.ErrUnauthorized)
+		}
+	}
+
+	// A broken store is a server error, not a bad key.
+	if _, err := AuthenticateAPIKey(ctx, failingStore{}, live, now); !errors.Is(err, errDown) {
+		t.Errorf("failing store: err = %v, want %v", err, errDown)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	store := memory.New()
+	reader := storeKey(t, store, []models.Scope{models.ScopeMoviesRead}, nil)
+	writer := storeKey(t, store, []models.Scope{models.ScopeMoviesRead, models.ScopeMoviesWrite}, nil)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Use(New(log, store, nil))
+	r.With(Require(log, models.ScopeMoviesRead)).Get("/movies", ok)
+	r.With(Require(log, models.ScopeMoviesWrite)).Post("/movies", ok)
+	r.With(Require(log, models.ScopeActorsWrite)).Post("/actors", ok)
+	r.With(RequireRole(log, models.RoleAdmin)).Get("/admin/api-keys", ok)
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		status int
+	}{
+		{"read with read scope", reader, http.MethodGet, "/movies", http.StatusOK},
+		{"write without write scope", reader, http.MethodPost, "/movies", http.StatusForbidden},
+		{"write with write scope", writer, http.MethodPost, "/movies", http.StatusOK},
+		{"actors without actors scope", writer, http.MethodPost, "/actors", http.StatusForbidden},
+		{"admin route", writer, http.MethodGet, "/admin/api-keys", http.StatusForbidden},
+		{"bad key", reader + "x", http.MethodGet, "/movies", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(APIKeyHeader, tt.key)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+
+	// The first use was recorded and is recent enough not to be rewritten.
+	key, err := store.GetAPIKey(context.Background(), strings.SplitN(reader, "_", 3)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > time.Minute {
+		t.Errorf("last used at %v, want the first request", key.LastUsedAt)
+	}
+	if touchDue(key, key.LastUsedAt.Add(time.Second)) || !touchDue(key, key.LastUsedAt.Add(touchInterval)) {
+		t.Errorf("touch due too often or never")
+	}
+}
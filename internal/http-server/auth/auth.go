@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/token"
+)
+
+type UserGetter interface {
+	GetUser(ctx context.Context, username string) (models.User, error)
+}
+
+// Store is what New needs from storage to check accounts and API keys.
+type Store interface {
+	UserGetter
+	APIKeyGetter
+}
+
+// AccessVerifier checks bearer tokens; *token.Manager implements it.
+type AccessVerifier interface {
+	ParseAccess(s string) (token.Claims, error)
+}
+
+type ctxKey struct{}
+
+// dummyHash is checked against when the username is unknown, so that a
+// missing account takes as long to reject as a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("cinema"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash stored for an account.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Principal is whoever a request was authenticated as: an account, or an
+// API key, which has scopes but no role.
+type Principal struct {
+	Name   string
+	Role   models.UserRole
+	Scopes []models.Scope
+}
+
+// Has reports whether p was granted scope.
+func (p Principal) Has(scope models.Scope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+func userPrincipal(user models.User) Principal {
+	return Principal{Name: user.Username, Role: user.Role, Scopes: user.Role.Scopes()}
+}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// PrincipalFrom returns the principal New authenticated for the request.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticate checks a username and password against the accounts in
+// storage. An unknown user and a wrong password both give
+// response.ErrUnauthorized.
+func Authenticate(ctx context.Context, users UserGetter, username, password string) (models.User, error) {
+	user, err := users.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return models.User{}, response.ErrUnauthorized
+		}
+
+		return models.User{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return models.User{}, response.ErrUnauthorized
+	}
+
+	return user, nil
+}
+
+// New authenticates every request with an API key in the X-API-Key header,
+// an access token from POST /auth/login in an "Authorization: Bearer"
+// header, or HTTP Basic credentials checked against the accounts in
+// storage. Requests without valid credentials are answered with 401. tokens
+// may be nil, which turns bearer tokens off.
+//
+// Clients should prefer tokens or API keys: Basic credentials cost a bcrypt
+// check, which is only skipped for a minute after the same credentials
+// last passed one.
+func New(log *slog.Logger, store Store, tokens AccessVerifier) func(next http.Handler) http.Handler {
+	log = log.With(
+		slog.String("component", "middleware/auth"),
+	)
+
+	log.Info("auth middleware enabled", slog.Bool("bearer", tokens != nil))
+
+	basic := newBasicCache(basicCacheTTL)
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := logger.FromContext(r.Context(), log)
+
+			if plaintext := r.Header.Get(APIKeyHeader); plaintext != "" {
+				now := time.Now().UTC()
+				key, err := AuthenticateAPIKey(r.Context(), store, plaintext, now)
+				if err != nil {
+					if errors.Is(err, response.ErrUnauthorized) {
+						unauthorized(w, r, log)
+						return
+					}
+
+					response.Error(w, r, log, err)
+					return
+				}
+
+				if touchDue(key, now) {
+					if err := store.TouchAPIKey(r.Context(), key.Id, now); err != nil {
+						log.Warn("failed to record api key use", slog.Int64("key_id", key.Id), slog.Any("error", err))
+					}
+				}
+
+				p := Principal{Name: "api-key:" + key.Name, Scopes: key.Scopes}
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+				return
+			}
+
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && tokens != nil {
+				claims, err := tokens.ParseAccess(bearer)
+				if err != nil {
+					log.Debug("bearer token rejected", slog.Any("error", err))
+					unauthorized(w, r, log)
+					return
+				}
+
+				user := models.User{Id: claims.UserId, Username: claims.Subject, Role: claims.Role}
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), userPrincipal(user))))
+				return
+			}
+
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				unauthorized(w, r, log)
+				return
+			}
+
+			now := time.Now()
+			user, ok := basic.get(username, password, now)
+			if !ok {
+				var err error
+				user, err = Authenticate(r.Context(), store, username, password)
+				if err != nil {
+					if errors.Is(err, response.ErrUnauthorized) {
+						unauthorized(w, r, log)
+						return
+					}
+
+					response.Error(w, r, log, err)
+					return
+				}
+				basic.put(username, password, user, now)
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), userPrincipal(user))))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Require lets through only requests whose principal holds scope; others
+// get 403. It must run after New.
+func Require(log *slog.Logger, scope models.Scope) func(next http.Handler) http.Handler {
+	return guard(log, func(p Principal) bool { return p.Has(scope) })
+}
+
+// RequireRole lets through only accounts whose role allows role; others,
+// API keys included, get 403. It must run after New.
+func RequireRole(log *slog.Logger, role models.UserRole) func(next http.Handler) http.Handler {
+	return guard(log, func(p Principal) bool { return p.Role.Allows(role) })
+}
+
+func guard(log *slog.Logger, allowed func(p Principal) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := logger.FromContext(r.Context(), log)
+
+			p, ok := PrincipalFrom(r.Context())
+			if !ok {
+				unauthorized(w, r, log)
+				return
+			}
+
+			if !allowed(p) {
+				response.Error(w, r, log, response.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, log *slog.Logger) {
+	w.Header().Add("WWW-Authenticate", `Bearer realm="cinema"`)
+	w.Header().Add("WWW-Authenticate", `Basic realm="cinema", charset="UTF-8"`)
+	response.Error(w, r, log, response.ErrUnauthorized)
+}
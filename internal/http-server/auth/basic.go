@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+// basicCacheTTL is how long a successful Basic check is reused. A changed
+// password or role takes effect on data routes after at most this long.
+const basicCacheTTL = time.Minute
+
+// maxBasicEntries bounds the cache; when it is full and nothing has
+// expired, it starts over empty.
+const maxBasicEntries = 1024
+
+// basicCache remembers accounts whose Basic credentials passed bcrypt
+// recently, so that a client sending them on every request doesn't pay for
+// bcrypt each time. Failures are never cached. Entries are keyed by an
+// HMAC of the credentials under a per-process secret, so no password or
+// plain password hash is kept in memory.
+type basicCache struct {
+	mu      sync.Mutex
+	secret  []byte
+	ttl     time.Duration
+	entries map[[sha256.Size]byte]basicEntry
+}
+
+type basicEntry struct {
+	user    models.User
+	expires time.Time
+}
+
+func newBasicCache(ttl time.Duration) *basicCache {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	return &basicCache{
+		secret:  secret,
+		ttl:     ttl,
+		entries: make(map[[sha256.Size]byte]basicEntry),
+	}
+}
+
+func (c *basicCache) key(username, password string) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+
+	var key [sha256.Size]byte
+	copy(key[:], mac.Sum(nil))
+
+	return key
+}
+
+// get returns the account cached for the credentials, if any and fresh.
+func (c *basicCache) get(username, password string, now time.Time) (models.User, bool) {
+	key := c.key(username, password)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return models.User{}, false
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return models.User{}, false
+	}
+
+	return entry.user, true
+}
+
+// put caches user as the account the credentials belong to.
+func (c *basicCache) put(username, password string, user models.User, now time.Time) {
+	key := c.key(username, password)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxBasicEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxBasicEntries {
+			clear(c.entries)
+		}
+	}
+
+	c.entries[key] = basicEntry{user: user, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+type countingStore struct {
+	user    models.User
+	lookups int
+}
+
+func (s *countingStore) GetUser(ctx context.Context, username string) (models.User, error) {
+	s.lookups++
+	if username != s.user.Username {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return s.user, nil
+}
+
+func (s *countingStore) GetAPIKey(ctx context.Context, prefix string) (models.APIKey, error) {
+	return models.APIKey{}, storage.ErrAPIKeyNotFound
+}
+
+func (s *countingStore) TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) error {
+	return nil
+}
+
+func TestBasicChecksAreCached(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &countingStore{user: models.User{Id: 1, Username: "ripley", PasswordHash: string(hash), Role: models.RoleUser}}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := New(log, store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := PrincipalFrom(r.Context()); !ok || p.Name != "ripley" {
+			t.Errorf("principal = %+v, %v", p, ok)
+		}
+	}))
+
+	request := func(password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/movies", nil)
+		req.SetBasicAuth("ripley", password)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for range 3 {
+		if code := request("secret"); code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+	}
+	if store.lookups != 1 {
+		t.Errorf("%d account lookups for one set of credentials, want 1", store.lookups)
+	}
+
+	for range 2 {
+		if code := request("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("wrong password: status = %d, want %d", code, http.StatusUnauthorized)
+		}
+	}
+	if store.lookups != 3 {
+		t.Errorf("%d account lookups, want every failure checked", store.lookups)
+	}
+}
+
+func TestBasicCacheExpires(t *testing.T) {
+	c := newBasicCache(time.Minute)
+	now := time.Now()
+	user := models.User{Username: "ripley"}
+
+	c.put("ripley", "secret", user, now)
+
+	if _, ok := c.get("ripley", "secret", now.Add(59*time.Second)); !ok {
+		t.Error("entry missing before the ttl")
+	}
+	if _, ok := c.get("ripley", "other", now); ok {
+		t.Error("entry found for another password")
+	}
+	if _, ok := c.get("ripley", "secret", now.Add(time.Minute)); ok {
+		t.Error("entry found after the ttl")
+	}
+}
+
+func TestBasicCacheBounded(t *testing.T) {
+	c := newBasicCache(time.Minute)
+	now := time.Now()
+
+	for i := range maxBasicEntries + 10 {
+		c.put("user", strconv.Itoa(i), models.User{}, now)
+	}
+
+	if len(c.entries) > maxBasicEntries {
+		t.Errorf("%d entries, want at most %d", len(c.entries), maxBasicEntries)
+	}
+}
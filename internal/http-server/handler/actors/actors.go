@@ -0,0 +1,225 @@
+package actors
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/patch"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+type ActorRequest struct {
+	Name   string `json:"name"`
+	Gender string `json:"gender"`
+	Birth  string `json:"birth"`
+}
+
+type ActorGetter interface {
+	GetActor(ctx context.Context, actorId int64) (models.Actor, error)
+}
+
+type ActorLister interface {
+	GetActors(ctx context.Context, page storage.Page) ([]models.Actor, string, error)
+}
+
+// ListResponse is one page of actors. NextCursor is empty on the last page.
+type ListResponse struct {
+	Items      []models.Actor `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type ActorSaver interface {
+	ActorGetter
+	CreateActor(ctx context.Context, name string, gender string, birth time.Time) (int64, error)
+}
+
+type ActorUpdater interface {
+	UpdateActor(ctx context.Context, actorId int64, patch storage.ActorPatch) (models.Actor, error)
+}
+
+type ActorDeleter interface {
+	DeleteActor(ctx context.Context, actorId int64) error
+}
+
+// ActorPatchRequest is the body of PATCH /actors/{id}.
+type ActorPatchRequest struct {
+	Name   patch.Field[string] `json:"name"`
+	Gender patch.Field[string] `json:"gender"`
+	Birth  patch.Field[string] `json:"birth"`
+}
+
+// List handles GET /actors, paging with ?limit= and the ?cursor= of the
+// previous page.
+func List(log *slog.Logger, s ActorLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.actors.List"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		page := storage.Page{Cursor: r.URL.Query().Get("cursor")}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 {
+				response.Error(w, r, log, response.BadRequest("limit must be a positive integer"))
+				return
+			}
+			page.Limit = n
+		}
+
+		actors, next, err := s.GetActors(r.Context(), page)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, ListResponse{Items: actors, NextCursor: next})
+	}
+}
+
+// Get handles GET /actors/{id}.
+func Get(log *slog.Logger, s ActorGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.actors.Get"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		actor, err := s.GetActor(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, actor)
+	}
+}
+
+// Create handles POST /actors.
+func Create(log *slog.Logger, s ActorSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.actors.Create"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req ActorRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		var v validation.Validator
+		v.Name(req.Name)
+		v.Gender(req.Gender)
+		birth, _ := v.Birth(req.Birth)
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		id, err := s.CreateActor(r.Context(), req.Name, req.Gender, birth)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		actor, err := s.GetActor(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, actor)
+	}
+}
+
+// Update handles PATCH /actors/{id}. The body is a JSON merge patch: absent
+// members are left unchanged, and only the members of ActorPatchRequest are
+// accepted.
+func Update(log *slog.Logger, s ActorUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.actors.Update"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		var req ActorPatchRequest
+		if err := patch.Decode(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		update, errs := req.toPatch()
+		if errs != nil {
+			response.Error(w, r, log, errs)
+			return
+		}
+
+		actor, err := s.UpdateActor(r.Context(), id, update)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, actor)
+	}
+}
+
+// toPatch applies the same rules as Create to the members present in req.
+func (req ActorPatchRequest) toPatch() (storage.ActorPatch, validation.Errors) {
+	var v validation.Validator
+	var p storage.ActorPatch
+
+	if name, ok := validation.Member(&v, "name", "a string", req.Name); ok {
+		v.Name(name)
+		p.Name = &name
+	}
+	if gender, ok := validation.Member(&v, "gender", "a string", req.Gender); ok {
+		v.Gender(gender)
+		p.Gender = &gender
+	}
+	if raw, ok := validation.Member(&v, "birth", "a string", req.Birth); ok {
+		if birth, ok := v.Birth(raw); ok {
+			p.Birth = &birth
+		}
+	}
+
+	return p, v.Errors()
+}
+
+// Delete handles DELETE /actors/{id}.
+func Delete(log *slog.Logger, s ActorDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.actors.Delete"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		if err := s.DeleteActor(r.Context(), id); err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,183 @@
+package actors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/handlertest"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	log := handlertest.Logger()
+	s := memory.New()
+
+	r := chi.NewRouter()
+	r.Get("/actors", List(log, s))
+	r.Post("/actors", Create(log, s))
+	r.Get("/actors/{id}", Get(log, s))
+	r.Patch("/actors/{id}", Update(log, s))
+	r.Delete("/actors/{id}", Delete(log, s))
+
+	return handlertest.NewServer(t, r)
+}
+
+func createActor(t *testing.T, srv *httptest.Server, name string) models.Actor {
+	t.Helper()
+
+	var actor models.Actor
+	status := handlertest.Do(t, srv, http.MethodPost, "/actors", `{"name":"`+name+`","gender":"female","birth":"1949-10-08"}`, &actor)
+	if status != http.StatusCreated {
+		t.Fatalf("create %q: status %d", name, status)
+	}
+
+	return actor
+}
+
+func TestCreateInvalid(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"no name", `{"gender":"female","birth":"1949-10-08"}`, []string{"name"}},
+		{"bad birth", `{"name":"Sigourney Weaver","gender":"female","birth":"08.10.1949"}`, []string{"birth"}},
+		{"future birth", `{"name":"Sigourney Weaver","gender":"female","birth":"2999-10-08"}`, []string{"birth"}},
+		{"unknown gender", `{"name":"Sigourney Weaver","gender":"f","birth":"1949-10-08"}`, []string{"gender"}},
+		{"everything", `{"name":"","gender":"","birth":""}`, []string{"name", "gender", "birth"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp handlertest.ValidationError
+			if status := handlertest.Do(t, srv, http.MethodPost, "/actors", tt.body, &resp); status != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", status, http.StatusUnprocessableEntity)
+			}
+			if resp.Code != response.CodeValidation || !slices.Equal(resp.Fields(), tt.fields) {
+				t.Errorf("code %q, errors %v, want %v", resp.Code, resp.Details, tt.fields)
+			}
+		})
+	}
+
+	var resp response.ErrorResponse
+	if status := handlertest.Do(t, srv, http.MethodPost, "/actors", `{"name":`, &resp); status != http.StatusBadRequest || resp.Code != response.CodeInvalidJSON {
+		t.Errorf("invalid json: status %d, code %q", status, resp.Code)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	srv := newTestServer(t)
+	actor := createActor(t, srv, "Sigourney Weaver")
+	path := "/actors/" + strconv.FormatInt(actor.Id, 10)
+
+	var updated models.Actor
+	if status := handlertest.Do(t, srv, http.MethodPatch, path, `{"name":"Susan Weaver"}`, &updated); status != http.StatusOK {
+		t.Fatalf("patch: status %d", status)
+	}
+	if updated.Name != "Susan Weaver" || updated.Gender != "female" || updated.Birth.Format("2006-01-02") != "1949-10-08" {
+		t.Errorf("patched %+v, want the new name and the old gender and birth", updated)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   string
+		fields []string
+	}{
+		{"unknown member", path, `{"surname":"Weaver"}`, http.StatusBadRequest, response.CodeInvalidJSON, nil},
+		{"null gender", path, `{"gender":null}`, http.StatusUnprocessableEntity, response.CodeValidation, []string{"gender"}},
+		{"bad values", path, `{"name":" ","gender":"f","birth":"2999-01-01"}`, http.StatusUnprocessableEntity, response.CodeValidation, []string{"name", "gender", "birth"}},
+		{"missing actor", "/actors/999", `{"name":"Nobody"}`, http.StatusNotFound, response.CodeNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp handlertest.ValidationError
+			if status := handlertest.Do(t, srv, http.MethodPatch, tt.path, tt.body, &resp); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if resp.Code != tt.code || !slices.Equal(resp.Fields(), tt.fields) {
+				t.Errorf("code %q, errors %v, want %q and %v", resp.Code, resp.Details, tt.code, tt.fields)
+			}
+		})
+	}
+}
+
+func TestGetDelete(t *testing.T) {
+	srv := newTestServer(t)
+	actor := createActor(t, srv, "Sigourney Weaver")
+	path := "/actors/" + strconv.FormatInt(actor.Id, 10)
+
+	var got models.Actor
+	if status := handlertest.Do(t, srv, http.MethodGet, path, "", &got); status != http.StatusOK || got.Name != "Sigourney Weaver" {
+		t.Fatalf("get: status %d, actor %+v", status, got)
+	}
+	if got.Birth.Format("2006-01-02") != "1949-10-08" {
+		t.Errorf("birth = %v, want 1949-10-08", got.Birth)
+	}
+
+	if status := handlertest.Do(t, srv, http.MethodDelete, path, "", nil); status != http.StatusNoContent {
+		t.Fatalf("delete: status %d", status)
+	}
+
+	var resp response.ErrorResponse
+	if status := handlertest.Do(t, srv, http.MethodGet, path, "", &resp); status != http.StatusNotFound || resp.Code != response.CodeNotFound {
+		t.Errorf("get after delete: status %d, code %q", status, resp.Code)
+	}
+	if status := handlertest.Do(t, srv, http.MethodDelete, path, "", &resp); status != http.StatusNotFound || resp.Code != response.CodeNotFound {
+		t.Errorf("delete again: status %d, code %q", status, resp.Code)
+	}
+	if status := handlertest.Do(t, srv, http.MethodGet, "/actors/abc", "", &resp); status != http.StatusBadRequest || resp.Code != response.CodeBadRequest {
+		t.Errorf("get invalid id: status %d, code %q", status, resp.Code)
+	}
+	if status := handlertest.Do(t, srv, http.MethodPost, "/actors", `{"name":`, &resp); status != http.StatusBadRequest || resp.Code != response.CodeInvalidJSON {
+		t.Errorf("create with invalid json: status %d, code %q", status, resp.Code)
+	}
+}
+
+func TestList(t *testing.T) {
+	srv := newTestServer(t)
+	for _, name := range []string{"Keanu Reeves", "Carrie-Anne Moss", "Laurence Fishburne"} {
+		createActor(t, srv, name)
+	}
+
+	var names []string
+	path := "/actors?limit=2"
+	for range 3 {
+		var page ListResponse
+		if status := handlertest.Do(t, srv, http.MethodGet, path, "", &page); status != http.StatusOK {
+			t.Fatalf("list: status %d", status)
+		}
+		if len(page.Items) > 2 {
+			t.Fatalf("page has %d actors, limit is 2", len(page.Items))
+		}
+		for _, actor := range page.Items {
+			names = append(names, actor.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/actors?limit=2&cursor=" + page.NextCursor
+	}
+
+	if want := []string{"Keanu Reeves", "Carrie-Anne Moss", "Laurence Fishburne"}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	var resp response.ErrorResponse
+	if status := handlertest.Do(t, srv, http.MethodGet, "/actors?cursor=nonsense", "", &resp); status != http.StatusBadRequest {
+		t.Errorf("bad cursor: status %d, want %d", status, http.StatusBadRequest)
+	}
+}
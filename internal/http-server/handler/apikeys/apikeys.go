@@ -0,0 +1,141 @@
+package apikeys
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/auth"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+// APIKeyRequest is the body of POST /admin/api-keys. ExpiresAt is RFC 3339;
+// a key without it never expires.
+type APIKeyRequest struct {
+	Name      string         `json:"name"`
+	Scopes    []models.Scope `json:"scopes"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+// CreateResponse is a new key. Key is the only time the plaintext is shown.
+type CreateResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+type APIKeySaver interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) (int64, error)
+}
+
+type APIKeyLister interface {
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+}
+
+type APIKeyDeleter interface {
+	DeleteAPIKey(ctx context.Context, keyId int64) error
+}
+
+// Create handles POST /admin/api-keys.
+func Create(log *slog.Logger, s APIKeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.apikeys.Create"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req APIKeyRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		now := time.Now().UTC()
+
+		var v validation.Validator
+		v.Name(req.Name)
+		v.Scopes(req.Scopes)
+		if req.ExpiresAt != nil {
+			v.Check(req.ExpiresAt.After(now), "expires_at", "must be in the future")
+		}
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		plaintext, prefix, hash, err := auth.NewAPIKey()
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		key := models.APIKey{
+			Name:      req.Name,
+			Prefix:    prefix,
+			Scopes:    req.Scopes,
+			CreatedAt: now,
+			ExpiresAt: req.ExpiresAt,
+			Hash:      hash,
+		}
+		if key.ExpiresAt != nil {
+			expiresAt := key.ExpiresAt.UTC()
+			key.ExpiresAt = &expiresAt
+		}
+
+		key.Id, err = s.CreateAPIKey(r.Context(), key)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		log.Info("api key created", slog.Int64("key_id", key.Id), slog.String("prefix", prefix))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, CreateResponse{APIKey: key, Key: plaintext})
+	}
+}
+
+// List handles GET /admin/api-keys. Hashes are never part of the listing.
+func List(log *slog.Logger, s APIKeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.apikeys.List"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		keys, err := s.ListAPIKeys(r.Context())
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, keys)
+	}
+}
+
+// Revoke handles DELETE /admin/api-keys/{id}. A revoked key stops working
+// on its next request.
+func Revoke(log *slog.Logger, s APIKeyDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.apikeys.Revoke"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		if err := s.DeleteAPIKey(r.Context(), id); err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("key_id", id))
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
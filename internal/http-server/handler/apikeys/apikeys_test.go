@@ -0,0 +1,141 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/auth"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/handlertest"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *memory.Storage) {
+	t.Helper()
+
+	s := memory.New()
+	log := handlertest.Logger()
+
+	r := chi.NewRouter()
+	r.Post("/admin/api-keys", Create(log, s))
+	r.Get("/admin/api-keys", List(log, s))
+	r.Delete("/admin/api-keys/{id}", Revoke(log, s))
+
+	return handlertest.NewServer(t, r), s
+}
+
+func TestCreate(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	var created CreateResponse
+	body := `{"name":"importer","scopes":["movies:read","movies:write"],"expires_at":"2099-01-01T02:00:00+02:00"}`
+	if status := handlertest.Do(t, srv, http.MethodPost, "/admin/api-keys", body, &created); status != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", status, http.StatusCreated)
+	}
+	if created.Id == 0 || created.Name != "importer" || len(created.Scopes) != 2 || created.Key == "" {
+		t.Errorf("created = %+v", created)
+	}
+	if created.ExpiresAt == nil || !created.ExpiresAt.Equal(time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expires at %v", created.ExpiresAt)
+	}
+
+	// The plaintext shown once is the key that authenticates.
+	key, err := auth.AuthenticateAPIKey(context.Background(), s, created.Key, time.Now())
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	if key.Id != created.Id || key.Hash == "" || key.Hash == created.Key {
+		t.Errorf("stored key = %+v", key)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{"blank name", `{"name":" ","scopes":["movies:read"]}`, http.StatusUnprocessableEntity, response.CodeValidation, "name"},
+		{"no scopes", `{"name":"importer","scopes":[]}`, http.StatusUnprocessableEntity, response.CodeValidation, "scopes"},
+		{"unknown scope", `{"name":"importer","scopes":["movies:read","movies:delete"]}`, http.StatusUnprocessableEntity, response.CodeValidation, "scopes[1]"},
+		{"expired", `{"name":"importer","scopes":["movies:read"],"expires_at":"2001-01-01T00:00:00Z"}`, http.StatusUnprocessableEntity, response.CodeValidation, "expires_at"},
+		{"invalid json", `{"name":`, http.StatusBadRequest, response.CodeInvalidJSON, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp struct {
+				Code    string            `json:"code"`
+				Details validation.Errors `json:"details"`
+			}
+			if status := handlertest.Do(t, srv, http.MethodPost, "/admin/api-keys", tt.body, &resp); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("code = %q, want %q", resp.Code, tt.code)
+			}
+			if tt.field != "" && (len(resp.Details) != 1 || resp.Details[0].Field != tt.field) {
+				t.Errorf("details = %v, want one error for %s", resp.Details, tt.field)
+			}
+		})
+	}
+}
+
+func TestListRevoke(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	var created CreateResponse
+	if status := handlertest.Do(t, srv, http.MethodPost, "/admin/api-keys", `{"name":"importer","scopes":["movies:read"]}`, &created); status != http.StatusCreated {
+		t.Fatalf("create: status %d", status)
+	}
+
+	var listed []map[string]interface{}
+	if status := handlertest.Do(t, srv, http.MethodGet, "/admin/api-keys", "", &listed); status != http.StatusOK {
+		t.Fatalf("list: status %d", status)
+	}
+	if len(listed) != 1 || listed[0]["name"] != "importer" || listed[0]["prefix"] == "" {
+		t.Fatalf("listed = %v", listed)
+	}
+	for _, member := range []string{"key", "hash", "Hash"} {
+		if _, ok := listed[0][member]; ok {
+			t.Errorf("listing shows %q", member)
+		}
+	}
+
+	path := "/admin/api-keys/" + strconv.FormatInt(created.Id, 10)
+	if status := handlertest.Do(t, srv, http.MethodDelete, path, "", nil); status != http.StatusNoContent {
+		t.Fatalf("revoke: status = %d, want %d", status, http.StatusNoContent)
+	}
+	if _, err := auth.AuthenticateAPIKey(context.Background(), s, created.Key, time.Now()); !errors.Is(err, response.ErrUnauthorized) {
+		t.Errorf("revoked key: err = %v, want %v", err, response.ErrUnauthorized)
+	}
+	if status := handlertest.Do(t, srv, http.MethodGet, "/admin/api-keys", "", &listed); status != http.StatusOK || len(listed) != 0 {
+		t.Errorf("list after revoke: status %d, %d keys", status, len(listed))
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{"revoked", path, http.StatusNotFound, response.CodeNotFound},
+		{"invalid id", "/admin/api-keys/abc", http.StatusBadRequest, response.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		var resp response.ErrorResponse
+		if status := handlertest.Do(t, srv, http.MethodDelete, tt.path, "", &resp); status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+		}
+		if resp.Code != tt.code {
+			t.Errorf("%s: code = %q, want %q", tt.name, resp.Code, tt.code)
+		}
+	}
+}
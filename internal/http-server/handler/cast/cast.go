@@ -0,0 +1,175 @@
+package cast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+// CastRequest is the body of PUT and POST /movies/{id}/actors. Cast is a
+// pointer so that a body without it can be told apart from an explicit
+// empty cast.
+type CastRequest struct {
+	Cast *[]models.CastEntry `json:"cast"`
+}
+
+type CastSetter interface {
+	GetMovie(ctx context.Context, filmId int64) (models.Movie, error)
+	ReplaceRules(ctx context.Context, movieId int64, cast []models.CastEntry) error
+}
+
+type CastAdder interface {
+	GetMovie(ctx context.Context, filmId int64) (models.Movie, error)
+	CreateRule(ctx context.Context, movieId int, cast []models.CastEntry) error
+}
+
+type CastDeleter interface {
+	DeleteRules(ctx context.Context, movieId int64) error
+}
+
+// Set handles PUT /movies/{id}/actors and replaces the movie's cast with
+// the entries listed in the request. An empty credit_type means supporting;
+// billing orders must not be negative or repeat, except 0 for unbilled.
+// The cast member is required, so clearing the cast takes an explicit
+// "cast": [], and unknown members are rejected.
+func Set(log *slog.Logger, s CastSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.cast.Set"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		var req CastRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+		if req.Cast == nil {
+			response.Error(w, r, log, response.BadRequest("cast is required, use [] to clear it"))
+			return
+		}
+		cast := *req.Cast
+
+		var v validation.Validator
+		v.Cast(cast)
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		if err := s.ReplaceRules(r.Context(), id, cast); err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		movie, err := s.GetMovie(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, movie)
+	}
+}
+
+// Add handles POST /movies/{id}/actors and links the listed actors to the
+// movie, keeping its current cast. An actor already in the cast is a
+// conflict, and billing orders must not repeat those already in use.
+func Add(log *slog.Logger, s CastAdder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.cast.Add"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		var req CastRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+		if req.Cast == nil || len(*req.Cast) == 0 {
+			response.Error(w, r, log, response.BadRequest("cast must list at least one actor"))
+			return
+		}
+		cast := *req.Cast
+
+		movie, err := s.GetMovie(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		var v validation.Validator
+		v.Cast(cast)
+		for i, entry := range cast {
+			for _, member := range movie.Actors {
+				if entry.BillingOrder > 0 && entry.BillingOrder == member.Role.BillingOrder {
+					v.Check(false, fmt.Sprintf("cast[%d].billing_order", i), fmt.Sprintf("is already used by actor %d", member.Id))
+				}
+			}
+		}
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		if err := s.CreateRule(r.Context(), int(id), cast); err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		movie, err = s.GetMovie(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, movie)
+	}
+}
+
+// Delete handles DELETE /movies/{id}/actors and removes the whole cast. A
+// movie without a cast is fine; a missing movie is not found.
+func Delete(log *slog.Logger, s CastDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.cast.Delete"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		if err := s.DeleteRules(r.Context(), id); err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,205 @@
+package cast
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/handlertest"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+// newTestServer serves the cast routes over a repository holding The
+// Matrix, with id 1, and Keanu Reeves and Carrie-Anne Moss, with ids 1
+// and 2.
+func newTestServer(t *testing.T) (*httptest.Server, *memory.Storage) {
+	t.Helper()
+
+	ctx := t.Context()
+	s := memory.New()
+	if _, err := s.CreateMovie(ctx, "The Matrix", "", time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC), 9); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateActor(ctx, "Keanu Reeves", "male", time.Date(1964, 9, 2, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateActor(ctx, "Carrie-Anne Moss", "female", time.Date(1967, 8, 21, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	log := handlertest.Logger()
+	r := chi.NewRouter()
+	r.Put("/movies/{id}/actors", Set(log, s))
+	r.Post("/movies/{id}/actors", Add(log, s))
+	r.Delete("/movies/{id}/actors", Delete(log, s))
+
+	return handlertest.NewServer(t, r), s
+}
+
+func TestSet(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		cast   int
+	}{
+		{"cast", `{"cast":[{"actor_id":1,"character":"Neo","credit_type":"lead"}]}`, http.StatusOK, "", 1},
+		{"no cast member", `{}`, http.StatusBadRequest, response.CodeBadRequest, 0},
+		{"null cast", `{"cast":null}`, http.StatusBadRequest, response.CodeBadRequest, 0},
+		{"misspelled member", `{"casts":[]}`, http.StatusBadRequest, response.CodeInvalidJSON, 0},
+		{"unknown entry member", `{"cast":[{"actor_id":1,"name":"Neo"}]}`, http.StatusBadRequest, response.CodeInvalidJSON, 0},
+		{"bad credit type", `{"cast":[{"actor_id":1,"credit_type":"star"}]}`, http.StatusUnprocessableEntity, response.CodeValidation, 0},
+		{"explicit empty cast", `{"cast":[]}`, http.StatusOK, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.code != "" {
+				var resp response.ErrorResponse
+				if status := handlertest.Do(t, srv, http.MethodPut, "/movies/1/actors", tt.body, &resp); status != tt.status {
+					t.Fatalf("status = %d, want %d", status, tt.status)
+				}
+				if resp.Code != tt.code {
+					t.Errorf("code = %q, want %q", resp.Code, tt.code)
+				}
+				return
+			}
+
+			var movie models.Movie
+			if status := handlertest.Do(t, srv, http.MethodPut, "/movies/1/actors", tt.body, &movie); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if len(movie.Actors) != tt.cast {
+				t.Errorf("cast has %d members, want %d", len(movie.Actors), tt.cast)
+			}
+		})
+	}
+
+	if movie, err := s.GetMovie(t.Context(), 1); err != nil || len(movie.Actors) != 0 {
+		t.Errorf("after explicit empty cast: %d members, err %v", len(movie.Actors), err)
+	}
+}
+
+func TestSetInvalid(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"negative billing order", `{"cast":[{"actor_id":1,"billing_order":-1}]}`, []string{"cast[0].billing_order"}},
+		{"duplicate billing order", `{"cast":[{"actor_id":1,"billing_order":1},{"actor_id":2,"billing_order":1}]}`, []string{"cast[1].billing_order"}},
+		{"long character", `{"cast":[{"actor_id":1,"character":"` + strings.Repeat("a", validation.MaxCharacterLen+1) + `"}]}`, []string{"cast[0].character"}},
+		{"every error", `{"cast":[{"actor_id":1,"billing_order":-1,"credit_type":"star"}]}`, []string{"cast[0].billing_order", "cast[0].credit_type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp handlertest.ValidationError
+			if status := handlertest.Do(t, srv, http.MethodPut, "/movies/1/actors", tt.body, &resp); status != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", status, http.StatusUnprocessableEntity)
+			}
+			if resp.Code != response.CodeValidation || !slices.Equal(resp.Fields(), tt.fields) {
+				t.Errorf("code %q, errors %v, want %v", resp.Code, resp.Details, tt.fields)
+			}
+		})
+	}
+
+	if movie, err := s.GetMovie(t.Context(), 1); err != nil || len(movie.Actors) != 0 {
+		t.Errorf("invalid casts were stored: %d members, err %v", len(movie.Actors), err)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	// The cases run in order: each one sees the cast the ones before left.
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   string
+		cast   int
+	}{
+		{"first actor", "/movies/1/actors", `{"cast":[{"actor_id":1,"character":"Neo","billing_order":1}]}`, http.StatusCreated, "", 1},
+		{"billing order taken", "/movies/1/actors", `{"cast":[{"actor_id":2,"billing_order":1}]}`, http.StatusUnprocessableEntity, response.CodeValidation, 0},
+		{"second actor", "/movies/1/actors", `{"cast":[{"actor_id":2,"character":"Trinity","billing_order":2}]}`, http.StatusCreated, "", 2},
+		{"already in the cast", "/movies/1/actors", `{"cast":[{"actor_id":1}]}`, http.StatusConflict, response.CodeConflict, 0},
+		{"unknown actor", "/movies/1/actors", `{"cast":[{"actor_id":99}]}`, http.StatusNotFound, response.CodeNotFound, 0},
+		{"missing movie", "/movies/2/actors", `{"cast":[{"actor_id":1}]}`, http.StatusNotFound, response.CodeNotFound, 0},
+		{"empty cast", "/movies/1/actors", `{"cast":[]}`, http.StatusBadRequest, response.CodeBadRequest, 0},
+		{"no cast member", "/movies/1/actors", `{}`, http.StatusBadRequest, response.CodeBadRequest, 0},
+		{"invalid id", "/movies/abc/actors", `{"cast":[{"actor_id":1}]}`, http.StatusBadRequest, response.CodeBadRequest, 0},
+		{"bad credit type", "/movies/1/actors", `{"cast":[{"actor_id":2,"credit_type":"star"}]}`, http.StatusUnprocessableEntity, response.CodeValidation, 0},
+	}
+
+	for _, tt := range tests {
+		if tt.code != "" {
+			var resp response.ErrorResponse
+			if status := handlertest.Do(t, srv, http.MethodPost, tt.path, tt.body, &resp); status != tt.status {
+				t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("%s: code = %q, want %q", tt.name, resp.Code, tt.code)
+			}
+			continue
+		}
+
+		var movie models.Movie
+		if status := handlertest.Do(t, srv, http.MethodPost, tt.path, tt.body, &movie); status != tt.status {
+			t.Fatalf("%s: status = %d, want %d", tt.name, status, tt.status)
+		}
+		if len(movie.Actors) != tt.cast {
+			t.Errorf("%s: cast has %d members, want %d", tt.name, len(movie.Actors), tt.cast)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	if status := handlertest.Do(t, srv, http.MethodPut, "/movies/1/actors", `{"cast":[{"actor_id":1}]}`, nil); status != http.StatusOK {
+		t.Fatalf("set: status %d", status)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{"cast", "/movies/1/actors", http.StatusNoContent, ""},
+		{"empty cast", "/movies/1/actors", http.StatusNoContent, ""},
+		{"missing movie", "/movies/2/actors", http.StatusNotFound, response.CodeNotFound},
+		{"invalid id", "/movies/abc/actors", http.StatusBadRequest, response.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp response.ErrorResponse
+			var out interface{}
+			if tt.code != "" {
+				out = &resp
+			}
+			if status := handlertest.Do(t, srv, http.MethodDelete, tt.path, "", out); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("code = %q, want %q", resp.Code, tt.code)
+			}
+		})
+	}
+}
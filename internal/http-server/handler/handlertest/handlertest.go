@@ -0,0 +1,70 @@
+// Package handlertest holds the HTTP fixtures shared by handler tests.
+package handlertest
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+// Logger returns a logger that drops everything, for handlers under test.
+func Logger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// NewServer serves h until the test ends.
+func NewServer(t *testing.T, h http.Handler) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// ValidationError is an error response with its field errors decoded.
+type ValidationError struct {
+	Code    string            `json:"code"`
+	Details validation.Errors `json:"details"`
+}
+
+// Fields lists the fields e has errors for, in order.
+func (e ValidationError) Fields() []string {
+	var fields []string
+	for _, fe := range e.Details {
+		fields = append(fields, fe.Field)
+	}
+
+	return fields
+}
+
+// Do sends body to srv and decodes the JSON answer into out, if given. It
+// returns the response status.
+func Do(t *testing.T, srv *httptest.Server, method, path, body string, out interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+)
+
+// checkTimeout bounds each readiness check, so that a hung database makes
+// the probe fail rather than time out.
+const checkTimeout = 2 * time.Second
+
+var (
+	errShuttingDown = errors.New("shutting down")
+	errPending      = errors.New("migrations pending")
+)
+
+// Check is one readiness condition; an error means the instance should not
+// get traffic.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// MigrationStatus is what Migrations needs; *migrate.Migrator implements it.
+type MigrationStatus interface {
+	Check() error
+	Pending() (bool, error)
+}
+
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Migrations fails while the database schema is not the one this binary
+// was built for.
+func Migrations(m MigrationStatus) func(ctx context.Context) error {
+	return func(context.Context) error {
+		if err := m.Check(); err != nil {
+			return err
+		}
+
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+		if pending {
+			return errPending
+		}
+
+		return nil
+	}
+}
+
+// Live handles GET /healthz: the process is up and serving.
+func Live(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{Status: "ok"})
+}
+
+// Ready handles GET /readyz. It answers 503 once shuttingDown is set or
+// when any check fails.
+func Ready(log *slog.Logger, shuttingDown *atomic.Bool, checks ...Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.health.Ready"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		resp := Response{Status: "ready", Checks: make(map[string]string, len(checks)+1)}
+		fail := func(name string, err error) {
+			resp.Status = "unavailable"
+			resp.Checks[name] = err.Error()
+			log.Warn("readiness check failed", slog.String("check", name), slog.Any("error", err))
+		}
+
+		if shuttingDown.Load() {
+			fail("server", errShuttingDown)
+		}
+
+		for _, check := range checks {
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			err := check.Run(ctx)
+			cancel()
+
+			if err != nil {
+				fail(check.Name, err)
+				continue
+			}
+			resp.Checks[check.Name] = "ok"
+		}
+
+		if resp.Status != "ready" {
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/handlertest"
+)
+
+func TestReadyDuringShutdownDelay(t *testing.T) {
+	var shuttingDown atomic.Bool
+
+	r := chi.NewRouter()
+	r.Get("/healthz", Live)
+	r.Get("/readyz", Ready(handlertest.Logger(), &shuttingDown))
+	r.Get("/movies", func(w http.ResponseWriter, r *http.Request) {})
+	srv := handlertest.NewServer(t, r)
+
+	var resp Response
+	if status := handlertest.Do(t, srv, http.MethodGet, "/readyz", "", &resp); status != http.StatusOK || resp.Status != "ready" {
+		t.Fatalf("before shutdown: status %d, %+v", status, resp)
+	}
+
+	// Shutdown has begun but the delay hasn't run out: readiness fails so
+	// that the load balancer moves away, while requests are still served.
+	shuttingDown.Store(true)
+
+	resp = Response{}
+	if status := handlertest.Do(t, srv, http.MethodGet, "/readyz", "", &resp); status != http.StatusServiceUnavailable {
+		t.Errorf("during the delay: status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if resp.Status != "unavailable" || resp.Checks["server"] != errShuttingDown.Error() {
+		t.Errorf("during the delay: %+v", resp)
+	}
+	if status := handlertest.Do(t, srv, http.MethodGet, "/healthz", "", &resp); status != http.StatusOK || resp.Status != "ok" {
+		t.Errorf("liveness during the delay: status %d, %+v", status, resp)
+	}
+	if status := handlertest.Do(t, srv, http.MethodGet, "/movies", "", nil); status != http.StatusOK {
+		t.Errorf("request during the delay: status %d", status)
+	}
+}
+
+func TestReadyChecks(t *testing.T) {
+	var shuttingDown atomic.Bool
+	errDown := errors.New("connection refused")
+
+	ok := Check{Name: "migrations", Run: func(ctx context.Context) error {
+		if _, bounded := ctx.Deadline(); !bounded {
+			t.Error("check runs without a deadline")
+		}
+		return nil
+	}}
+	failing := Check{Name: "database", Run: func(ctx context.Context) error { return errDown }}
+
+	tests := []struct {
+		name   string
+		checks []Check
+		status int
+		want   map[string]string
+	}{
+		{"no checks", nil, http.StatusOK, nil},
+		{"passing", []Check{ok}, http.StatusOK, map[string]string{"migrations": "ok"}},
+		{"failing", []Check{ok, failing}, http.StatusServiceUnavailable, map[string]string{"migrations": "ok", "database": errDown.Error()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Get("/readyz", Ready(handlertest.Logger(), &shuttingDown, tt.checks...))
+			srv := handlertest.NewServer(t, r)
+
+			var resp Response
+			if status := handlertest.Do(t, srv, http.MethodGet, "/readyz", "", &resp); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if len(resp.Checks) != len(tt.want) {
+				t.Errorf("checks = %v, want %v", resp.Checks, tt.want)
+			}
+			for name, want := range tt.want {
+				if resp.Checks[name] != want {
+					t.Errorf("check %s = %q, want %q", name, resp.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+type migrationStatus struct {
+	check   error
+	pending bool
+}
+
+func (m migrationStatus) Check() error           { return m.check }
+func (m migrationStatus) Pending() (bool, error) { return m.pending, nil }
+
+func TestMigrations(t *testing.T) {
+	errAhead := errors.New("database ahead")
+
+	tests := []struct {
+		name   string
+		status migrationStatus
+		want   error
+	}{
+		{"current", migrationStatus{}, nil},
+		{"pending", migrationStatus{pending: true}, errPending},
+		{"ahead", migrationStatus{check: errAhead}, errAhead},
+	}
+
+	for _, tt := range tests {
+		if err := Migrations(tt.status)(context.Background()); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package loglevel
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+// LevelRequest is the body of PUT /admin/log-level, and what both
+// endpoints answer with. Level is debug, info, warn or error.
+type LevelRequest struct {
+	Level string `json:"level"`
+}
+
+// Leveler is the adjustable level of the running logger; *slog.LevelVar
+// implements it.
+type Leveler interface {
+	Level() slog.Level
+	Set(level slog.Level)
+}
+
+// Get handles GET /admin/log-level.
+func Get(l Leveler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, LevelRequest{Level: l.Level().String()})
+	}
+}
+
+// Set handles PUT /admin/log-level. The new level takes effect at once.
+func Set(log *slog.Logger, l Leveler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.loglevel.Set"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req LevelRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		var level slog.Level
+		err := level.UnmarshalText([]byte(req.Level))
+
+		var v validation.Validator
+		v.Check(err == nil, "level", "must be debug, info, warn or error")
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		old := l.Level()
+		l.Set(level)
+		log.Warn("log level changed", slog.String("from", old.String()), slog.String("to", level.String()))
+
+		render.JSON(w, r, LevelRequest{Level: level.String()})
+	}
+}
@@ -0,0 +1,366 @@
+package movies
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/patch"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+type MovieRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Date        string `json:"date"`
+	Rating      int    `json:"rating"`
+}
+
+type MovieGetter interface {
+	GetMovie(ctx context.Context, filmId int64) (models.Movie, error)
+}
+
+type MovieLister interface {
+	GetMovieByFragment(ctx context.Context, fragmentType string, fragment string, fuzzy bool, limit int) ([]models.SearchResult, error)
+	GetMoviesSorted(ctx context.Context, filter storage.MovieFilter, sortBy string, page storage.Page) ([]models.Movie, string, error)
+}
+
+// ListResponse is one page of movies. NextCursor is empty on the last page.
+type ListResponse struct {
+	Items      []models.Movie `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// FragmentResponse holds the results of a ?fragment-type= search.
+type FragmentResponse struct {
+	Items []models.SearchResult `json:"items"`
+}
+
+type MovieSaver interface {
+	MovieGetter
+	CreateMovie(ctx context.Context, title string, description string, date time.Time, rating int8) (int64, error)
+}
+
+type MovieUpdater interface {
+	UpdateMovie(ctx context.Context, filmId int64, patch storage.MoviePatch) (models.Movie, error)
+}
+
+type MovieDeleter interface {
+	DeliteMovie(ctx context.Context, filmId int) error
+}
+
+// MoviePatchRequest is the body of PATCH /movies/{id}.
+type MoviePatchRequest struct {
+	Title       patch.Field[string] `json:"title"`
+	Description patch.Field[string] `json:"description"`
+	Date        patch.Field[string] `json:"date"`
+	Rating      patch.Field[int]    `json:"rating"`
+}
+
+// listParams are the query parameters GET /movies understands. Anything
+// else is rejected so that a typo in a filter name is not silently ignored.
+var listParams = map[string]bool{
+	"title":         true,
+	"actor":         true,
+	"year_from":     true,
+	"year_to":       true,
+	"rating_min":    true,
+	"rating_max":    true,
+	"sort":          true,
+	"limit":         true,
+	"cursor":        true,
+	"fragment-type": true,
+	"fragment":      true,
+	"match":         true,
+}
+
+// List handles GET /movies. It pages through movies matching every given
+// filter (?title=, ?actor=, ?year_from=, ?year_to=, ?rating_min=,
+// ?rating_max=), ordered by ?sort= (for example "rating:desc,title"), using
+// ?limit= and the ?cursor= of the previous page. The older
+// ?fragment-type=title|actor&fragment=... search is still served scored and
+// best first, as a single page of at most ?limit= results; ?match=fuzzy
+// makes it tolerate typos and Cyrillic/Latin transliterations, though
+// every word must still start with the right letter.
+func List(log *slog.Logger, s MovieLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.movies.List"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		query := r.URL.Query()
+		for key := range query {
+			if !listParams[key] {
+				response.Error(w, r, log, response.BadRequest(fmt.Sprintf("unknown query parameter %q", key)))
+				return
+			}
+		}
+
+		page, err := pageParams(r)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		if fragmentType := query.Get("fragment-type"); fragmentType != "" {
+			if page.Cursor != "" {
+				response.Error(w, r, log, response.BadRequest("cursor cannot be used with fragment-type"))
+				return
+			}
+
+			var fuzzy bool
+			switch query.Get("match") {
+			case "", "exact":
+			case "fuzzy":
+				fuzzy = true
+			default:
+				response.Error(w, r, log, response.BadRequest("match must be exact or fuzzy"))
+				return
+			}
+
+			results, err := s.GetMovieByFragment(r.Context(), fragmentType, query.Get("fragment"), fuzzy, page.Size())
+			if err != nil {
+				response.Error(w, r, log, err)
+				return
+			}
+
+			render.JSON(w, r, FragmentResponse{Items: results})
+			return
+		}
+
+		filter, err := filterParams(r)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		if _, err := storage.ParseMovieOrder(query.Get("sort")); err != nil {
+			response.Error(w, r, log, response.BadRequest(err.Error()))
+			return
+		}
+
+		movies, next, err := s.GetMoviesSorted(r.Context(), filter, query.Get("sort"), page)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, ListResponse{Items: movies, NextCursor: next})
+	}
+}
+
+// filterParams reads the movie filters from the query string. A range whose
+// lower bound is above its upper one is a validation error rather than an
+// empty page.
+func filterParams(r *http.Request) (storage.MovieFilter, error) {
+	query := r.URL.Query()
+
+	filter := storage.MovieFilter{
+		Title: query.Get("title"),
+		Actor: query.Get("actor"),
+	}
+
+	for key, dst := range map[string]**int{
+		"year_from":  &filter.YearFrom,
+		"year_to":    &filter.YearTo,
+		"rating_min": &filter.RatingMin,
+		"rating_max": &filter.RatingMax,
+	} {
+		value := query.Get(key)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return storage.MovieFilter{}, response.BadRequest(fmt.Sprintf("%s must be an integer", key))
+		}
+		*dst = &n
+	}
+
+	var v validation.Validator
+	if filter.YearFrom != nil && filter.YearTo != nil {
+		v.Check(*filter.YearFrom <= *filter.YearTo, "year_from", "must not be after year_to")
+	}
+	if filter.RatingMin != nil && filter.RatingMax != nil {
+		v.Check(*filter.RatingMin <= *filter.RatingMax, "rating_min", "must not be above rating_max")
+	}
+	if !v.Valid() {
+		return storage.MovieFilter{}, v.Errors()
+	}
+
+	return filter, nil
+}
+
+// pageParams reads ?limit= and ?cursor=. A missing limit means the default
+// page size.
+func pageParams(r *http.Request) (storage.Page, error) {
+	query := r.URL.Query()
+
+	page := storage.Page{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return storage.Page{}, response.BadRequest("limit must be a positive integer")
+		}
+		page.Limit = n
+	}
+
+	return page, nil
+}
+
+// Get handles GET /movies/{id}.
+func Get(log *slog.Logger, s MovieGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.movies.Get"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		movie, err := s.GetMovie(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, movie)
+	}
+}
+
+// Create handles POST /movies.
+func Create(log *slog.Logger, s MovieSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.movies.Create"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req MovieRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		var v validation.Validator
+		v.Title(req.Title)
+		v.Description(req.Description)
+		v.Rating(req.Rating)
+		date, _ := v.Date("date", req.Date)
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		id, err := s.CreateMovie(r.Context(), req.Title, req.Description, date, int8(req.Rating))
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		movie, err := s.GetMovie(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, movie)
+	}
+}
+
+// Update handles PATCH /movies/{id}. The body is a JSON merge patch: absent
+// members are left unchanged, and only the members of MoviePatchRequest are
+// accepted. description may be null to clear it.
+func Update(log *slog.Logger, s MovieUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.movies.Update"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		var req MoviePatchRequest
+		if err := patch.Decode(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		update, errs := req.toPatch()
+		if errs != nil {
+			response.Error(w, r, log, errs)
+			return
+		}
+
+		movie, err := s.UpdateMovie(r.Context(), id, update)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, movie)
+	}
+}
+
+// toPatch applies the same rules as Create to the members present in req.
+func (req MoviePatchRequest) toPatch() (storage.MoviePatch, validation.Errors) {
+	var v validation.Validator
+	var p storage.MoviePatch
+
+	if title, ok := validation.Member(&v, "title", "a string", req.Title); ok {
+		v.Title(title)
+		p.Title = &title
+	}
+	if req.Description.Null {
+		p.Description = new(string)
+	} else if description, ok := validation.Member(&v, "description", "a string", req.Description); ok {
+		v.Description(description)
+		p.Description = &description
+	}
+	if raw, ok := validation.Member(&v, "date", "a string", req.Date); ok {
+		if date, ok := v.Date("date", raw); ok {
+			p.Date = &date
+		}
+	}
+	if rating, ok := validation.Member(&v, "rating", "an integer", req.Rating); ok {
+		v.Rating(rating)
+		r := int8(rating)
+		p.Rating = &r
+	}
+
+	return p, v.Errors()
+}
+
+// Delete handles DELETE /movies/{id}.
+func Delete(log *slog.Logger, s MovieDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.movies.Delete"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			response.Error(w, r, log, response.BadRequest("invalid id"))
+			return
+		}
+
+		if err := s.DeliteMovie(r.Context(), int(id)); err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,280 @@
+package movies
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/handlertest"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	log := handlertest.Logger()
+	s := memory.New()
+
+	r := chi.NewRouter()
+	r.Get("/movies", List(log, s))
+	r.Post("/movies", Create(log, s))
+	r.Get("/movies/{id}", Get(log, s))
+	r.Patch("/movies/{id}", Update(log, s))
+	r.Delete("/movies/{id}", Delete(log, s))
+
+	return handlertest.NewServer(t, r)
+}
+
+func createMovie(t *testing.T, srv *httptest.Server, title, date string, rating int) models.Movie {
+	t.Helper()
+
+	body, _ := json.Marshal(MovieRequest{Title: title, Description: "about " + title, Date: date, Rating: rating})
+
+	var movie models.Movie
+	if status := handlertest.Do(t, srv, http.MethodPost, "/movies", string(body), &movie); status != http.StatusCreated {
+		t.Fatalf("create %q: status %d", title, status)
+	}
+
+	return movie
+}
+
+func TestCreateInvalid(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"bad rating", `{"title":"Dune","description":"","date":"1984-12-14","rating":11}`, []string{"rating"}},
+		{"bad date", `{"title":"Dune","description":"","date":"14.12.1984","rating":6}`, []string{"date"}},
+		{"no title", `{"description":"","date":"1984-12-14","rating":6}`, []string{"title"}},
+		{"long title", `{"title":"` + strings.Repeat("a", validation.MaxTitleLen+1) + `","date":"1984-12-14","rating":6}`, []string{"title"}},
+		{"everything", `{"title":" ","description":"` + strings.Repeat("a", validation.MaxDescriptionLen+1) + `","date":"","rating":-1}`,
+			[]string{"title", "description", "rating", "date"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp handlertest.ValidationError
+			if status := handlertest.Do(t, srv, http.MethodPost, "/movies", tt.body, &resp); status != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", status, http.StatusUnprocessableEntity)
+			}
+			if resp.Code != response.CodeValidation || !slices.Equal(resp.Fields(), tt.fields) {
+				t.Errorf("code %q, errors %v, want %v", resp.Code, resp.Details, tt.fields)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := newTestServer(t)
+	createMovie(t, srv, "Alien", "1979-05-25", 8)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"duplicate title", http.MethodPost, "/movies", `{"title":"Alien","description":"","date":"1979-05-25","rating":8}`, http.StatusConflict, response.CodeConflict},
+		{"invalid json", http.MethodPost, "/movies", `{"title":`, http.StatusBadRequest, response.CodeInvalidJSON},
+		{"invalid id", http.MethodGet, "/movies/abc", "", http.StatusBadRequest, response.CodeBadRequest},
+		{"get missing", http.MethodGet, "/movies/999", "", http.StatusNotFound, response.CodeNotFound},
+		{"patch missing", http.MethodPatch, "/movies/999", `{"rating":5}`, http.StatusNotFound, response.CodeNotFound},
+		{"delete missing", http.MethodDelete, "/movies/999", "", http.StatusNotFound, response.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp response.ErrorResponse
+			if status := handlertest.Do(t, srv, tt.method, tt.path, tt.body, &resp); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("code = %q, want %q", resp.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	srv := newTestServer(t)
+	movie := createMovie(t, srv, "Alien", "1979-05-25", 8)
+	path := "/movies/" + itoa(movie.Id)
+
+	var updated models.Movie
+	if status := handlertest.Do(t, srv, http.MethodPatch, path, `{"rating":9,"description":null}`, &updated); status != http.StatusOK {
+		t.Fatalf("patch: status %d", status)
+	}
+	if updated.Rating != 9 || updated.Description != "" || updated.Title != "Alien" || updated.Date.Format("2006-01-02") != "1979-05-25" {
+		t.Errorf("patched %+v, want rating 9, no description, the rest unchanged", updated)
+	}
+
+	var unchanged models.Movie
+	if status := handlertest.Do(t, srv, http.MethodPatch, path, `{}`, &unchanged); status != http.StatusOK || unchanged.Rating != 9 || unchanged.Title != "Alien" {
+		t.Errorf("empty patch: status %d, movie %+v", status, unchanged)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		fields []string
+	}{
+		{"unknown member", `{"stars":5}`, http.StatusBadRequest, response.CodeInvalidJSON, nil},
+		{"null title", `{"title":null}`, http.StatusUnprocessableEntity, response.CodeValidation, []string{"title"}},
+		{"wrong type", `{"rating":"nine"}`, http.StatusUnprocessableEntity, response.CodeValidation, []string{"rating"}},
+		{"bad values", `{"title":"","date":"1979","rating":11}`, http.StatusUnprocessableEntity, response.CodeValidation, []string{"title", "date", "rating"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp handlertest.ValidationError
+			if status := handlertest.Do(t, srv, http.MethodPatch, path, tt.body, &resp); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if resp.Code != tt.code || !slices.Equal(resp.Fields(), tt.fields) {
+				t.Errorf("code %q, errors %v, want %q and %v", resp.Code, resp.Details, tt.code, tt.fields)
+			}
+		})
+	}
+
+	var got models.Movie
+	if status := handlertest.Do(t, srv, http.MethodGet, path, "", &got); status != http.StatusOK || got.Rating != 9 || got.Title != "Alien" {
+		t.Errorf("after rejected patches: status %d, movie %+v", status, got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	srv := newTestServer(t)
+	movie := createMovie(t, srv, "Alien", "1979-05-25", 8)
+	path := "/movies/" + itoa(movie.Id)
+
+	if status := handlertest.Do(t, srv, http.MethodDelete, path, "", nil); status != http.StatusNoContent {
+		t.Fatalf("delete: status %d", status)
+	}
+
+	var resp response.ErrorResponse
+	if status := handlertest.Do(t, srv, http.MethodGet, path, "", &resp); status != http.StatusNotFound || resp.Code != response.CodeNotFound {
+		t.Errorf("get after delete: status %d, code %q", status, resp.Code)
+	}
+	if status := handlertest.Do(t, srv, http.MethodDelete, path, "", &resp); status != http.StatusNotFound || resp.Code != response.CodeNotFound {
+		t.Errorf("delete again: status %d, code %q", status, resp.Code)
+	}
+}
+
+func TestList(t *testing.T) {
+	srv := newTestServer(t)
+	createMovie(t, srv, "Alien", "1979-05-25", 8)
+	createMovie(t, srv, "Brazil", "1985-02-20", 8)
+	createMovie(t, srv, "Casablanca", "1942-11-26", 9)
+
+	var titles []string
+	query := url.Values{"sort": {"title:desc"}, "limit": {"2"}}
+	for range 3 {
+		var page ListResponse
+		if status := handlertest.Do(t, srv, http.MethodGet, "/movies?"+query.Encode(), "", &page); status != http.StatusOK {
+			t.Fatalf("list: status %d", status)
+		}
+		for _, movie := range page.Items {
+			titles = append(titles, movie.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+
+	if want := []string{"Casablanca", "Brazil", "Alien"}; !slices.Equal(titles, want) {
+		t.Errorf("titles = %v, want %v", titles, want)
+	}
+
+	var fragment FragmentResponse
+	if status := handlertest.Do(t, srv, http.MethodGet, "/movies?fragment-type=title&fragment=a&limit=2", "", &fragment); status != http.StatusOK || len(fragment.Items) != 2 {
+		t.Errorf("fragment search with limit=2: status %d, %d items", status, len(fragment.Items))
+	}
+
+	for _, bad := range []string{
+		"?sort=id", "?limit=0", "?cursor=nonsense",
+		"?fragment-type=title&fragment=a&limit=-1",
+		"?fragment-type=title&fragment=a&cursor=nonsense",
+		"?fragment-type=title&fragment=a&match=roughly",
+	} {
+		var resp response.ErrorResponse
+		if status := handlertest.Do(t, srv, http.MethodGet, "/movies"+bad, "", &resp); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", bad, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	srv := newTestServer(t)
+	createMovie(t, srv, "Alien", "1979-05-25", 8)
+	createMovie(t, srv, "Brazil", "1985-02-20", 8)
+	createMovie(t, srv, "Casablanca", "1942-11-26", 9)
+
+	tests := []struct {
+		query  string
+		titles []string
+	}{
+		{"?rating_min=9", []string{"Casablanca"}},
+		{"?rating_min=8&rating_max=8&sort=title", []string{"Alien", "Brazil"}},
+		{"?year_from=1979&year_to=1979", []string{"Alien"}},
+		{"?year_from=1950&sort=date:desc", []string{"Brazil", "Alien"}},
+	}
+	for _, tt := range tests {
+		var page ListResponse
+		if status := handlertest.Do(t, srv, http.MethodGet, "/movies"+tt.query, "", &page); status != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.query, status)
+		}
+		var titles []string
+		for _, movie := range page.Items {
+			titles = append(titles, movie.Title)
+		}
+		if !slices.Equal(titles, tt.titles) {
+			t.Errorf("%s: titles = %v, want %v", tt.query, titles, tt.titles)
+		}
+	}
+
+	for _, bad := range []string{"?rating_min=high", "?year_to=1980.5", "?colour=red"} {
+		var resp response.ErrorResponse
+		if status := handlertest.Do(t, srv, http.MethodGet, "/movies"+bad, "", &resp); status != http.StatusBadRequest || resp.Code != response.CodeBadRequest {
+			t.Errorf("%s: status %d, code %q", bad, status, resp.Code)
+		}
+	}
+
+	inverted := []struct {
+		query  string
+		fields []string
+	}{
+		{"?year_from=1990&year_to=1980", []string{"year_from"}},
+		{"?rating_min=9&rating_max=2", []string{"rating_min"}},
+		{"?year_from=1990&year_to=1980&rating_min=9&rating_max=2", []string{"year_from", "rating_min"}},
+	}
+	for _, tt := range inverted {
+		var resp handlertest.ValidationError
+		if status := handlertest.Do(t, srv, http.MethodGet, "/movies"+tt.query, "", &resp); status != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want %d", tt.query, status, http.StatusUnprocessableEntity)
+		}
+		if resp.Code != response.CodeValidation || !slices.Equal(resp.Fields(), tt.fields) {
+			t.Errorf("%s: code %q, errors %v, want %v", tt.query, resp.Code, resp.Details, tt.fields)
+		}
+	}
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+type MovieSearcher interface {
+	SearchMovies(ctx context.Context, q string, limit int) ([]models.SearchResult, error)
+}
+
+type Response struct {
+	Items []models.SearchResult `json:"items"`
+}
+
+// Search handles GET /search?q=, returning movies ranked by relevance with
+// an HTML snippet each: escaped text with the matched words wrapped in
+// <mark></mark>.
+func Search(log *slog.Logger, s MovieSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.search.Search"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+		if q == "" {
+			response.Error(w, r, log, response.BadRequest("q must not be empty"))
+			return
+		}
+
+		page := storage.Page{}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 {
+				response.Error(w, r, log, response.BadRequest("limit must be a positive integer"))
+				return
+			}
+			page.Limit = n
+		}
+
+		results, err := s.SearchMovies(r.Context(), q, page.Size())
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, Response{Items: results})
+	}
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/auth"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/token"
+)
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Store is what the session handlers need from storage: accounts, and the
+// refresh tokens that have been issued but not used yet.
+type Store interface {
+	auth.UserGetter
+	CreateRefreshToken(ctx context.Context, tokenId string, userId int64, expiresAt time.Time) error
+	UseRefreshToken(ctx context.Context, tokenId string) error
+}
+
+type TokenIssuer interface {
+	Issue(user models.User) (token.Pair, error)
+}
+
+type TokenRefresher interface {
+	TokenIssuer
+	ParseRefresh(s string) (token.Claims, error)
+}
+
+// Login handles POST /auth/login, trading a username and password for an
+// access and a refresh token.
+func Login(log *slog.Logger, users Store, tokens TokenIssuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.session.Login"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req LoginRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		user, err := auth.Authenticate(r.Context(), users, req.Username, req.Password)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		pair, err := issue(r.Context(), users, tokens, user)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, pair)
+	}
+}
+
+// Refresh handles POST /auth/refresh, trading a refresh token for a new
+// pair. Each refresh token works once: it is used up here and the new pair
+// carries its replacement. The account is looked up again so that a changed
+// role applies and a deleted or recreated account can't refresh.
+func Refresh(log *slog.Logger, users Store, tokens TokenRefresher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.session.Refresh"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req RefreshRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+
+		claims, err := tokens.ParseRefresh(req.RefreshToken)
+		if err != nil {
+			log.Debug("refresh token rejected", slog.Any("error", err))
+			response.Error(w, r, log, response.ErrUnauthorized)
+			return
+		}
+
+		if err := users.UseRefreshToken(r.Context(), claims.ID); err != nil {
+			if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+				log.Warn("refresh token already used", slog.String("user", claims.Subject))
+				err = response.ErrUnauthorized
+			}
+			response.Error(w, r, log, err)
+			return
+		}
+
+		user, err := users.GetUser(r.Context(), claims.Subject)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				err = response.ErrUnauthorized
+			}
+			response.Error(w, r, log, err)
+			return
+		}
+		if user.Id != claims.UserId {
+			response.Error(w, r, log, response.ErrUnauthorized)
+			return
+		}
+
+		pair, err := issue(r.Context(), users, tokens, user)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.JSON(w, r, pair)
+	}
+}
+
+// issue signs a new pair for user and records its refresh token as unused.
+func issue(ctx context.Context, store Store, tokens TokenIssuer, user models.User) (token.Pair, error) {
+	pair, err := tokens.Issue(user)
+	if err != nil {
+		return token.Pair{}, err
+	}
+
+	if err := store.CreateRefreshToken(ctx, pair.RefreshId, user.Id, pair.RefreshExpiresAt); err != nil {
+		return token.Pair{}, err
+	}
+
+	return pair, nil
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/config"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/auth"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/handler/handlertest"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+	"github.com/rmnvlv/golang-cinema-api/internal/token"
+)
+
+func newHandlers(t *testing.T) (login, refresh http.HandlerFunc) {
+	t.Helper()
+
+	log := handlertest.Logger()
+	store := memory.New()
+
+	hash, err := auth.HashPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser(context.Background(), "alice", hash, models.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := token.New(config.JWT{
+		Issuer:     "test",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+		Keys:       []config.JWTKey{{ID: "k1", Alg: token.AlgHS256, Secret: strings.Repeat("s", token.MinSecretLen)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Login(log, store, tokens), Refresh(log, store, tokens)
+}
+
+// post sends body to h and decodes a 200 response into a pair.
+func post(t *testing.T, h http.HandlerFunc, body interface{}) (int, token.Pair) {
+	t.Helper()
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(raw)))
+
+	var pair token.Pair
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&pair); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rec.Code, pair
+}
+
+func TestLogin(t *testing.T) {
+	login, _ := newHandlers(t)
+
+	if code, _ := post(t, login, LoginRequest{Username: "alice", Password: "wrong"}); code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	code, pair := post(t, login, LoginRequest{Username: "alice", Password: "secret123"})
+	if code != http.StatusOK || pair.AccessToken == "" || pair.RefreshToken == "" {
+		t.Fatalf("login: status = %d, pair = %+v", code, pair)
+	}
+}
+
+func TestRefreshRotates(t *testing.T) {
+	login, refresh := newHandlers(t)
+
+	_, first := post(t, login, LoginRequest{Username: "alice", Password: "secret123"})
+
+	code, second := post(t, refresh, RefreshRequest{RefreshToken: first.RefreshToken})
+	if code != http.StatusOK {
+		t.Fatalf("refresh: status = %d, want %d", code, http.StatusOK)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Errorf("refresh returned the token it was given")
+	}
+
+	if code, _ := post(t, refresh, RefreshRequest{RefreshToken: first.RefreshToken}); code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	code, third := post(t, refresh, RefreshRequest{RefreshToken: second.RefreshToken})
+	if code != http.StatusOK || third.RefreshToken == "" {
+		t.Errorf("refresh with the replacement: status = %d", code)
+	}
+}
+
+func TestRefreshRejectsAccessToken(t *testing.T) {
+	login, refresh := newHandlers(t)
+
+	_, pair := post(t, login, LoginRequest{Username: "alice", Password: "secret123"})
+
+	if code, _ := post(t, refresh, RefreshRequest{RefreshToken: pair.AccessToken}); code != http.StatusUnauthorized {
+		t.Errorf("access token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,66 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/auth"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger"
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/response"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+type UserRequest struct {
+	Username string          `json:"username"`
+	Password string          `json:"password"`
+	Role     models.UserRole `json:"role"`
+}
+
+type UserSaver interface {
+	CreateUser(ctx context.Context, username string, passwordHash string, role models.UserRole) (int64, error)
+}
+
+// Create handles POST /admin/users. An empty role means user.
+func Create(log *slog.Logger, s UserSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.users.Create"
+		log := logger.FromContext(r.Context(), log).With(slog.String("op", op))
+
+		var req UserRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			response.Error(w, r, log, response.InvalidJSON(err))
+			return
+		}
+		if req.Role == "" {
+			req.Role = models.RoleUser
+		}
+
+		var v validation.Validator
+		v.Username(req.Username)
+		v.Password(req.Password)
+		v.Role(req.Role)
+		if !v.Valid() {
+			response.Error(w, r, log, v.Errors())
+			return
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		id, err := s.CreateUser(r.Context(), req.Username, hash, req.Role)
+		if err != nil {
+			response.Error(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, models.User{Id: id, Username: req.Username, Role: req.Role})
+	}
+}
@@ -0,0 +1,14 @@
+package version
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/buildinfo"
+)
+
+// Get handles GET /version.
+func Get(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, buildinfo.Get())
+}
@@ -1,98 +1,189 @@
 package pretty
 
-// import (
-// 	"context"
-// 	"encoding/json"
-// 	"io"
-// 	stdLog "log"
-
-// 	"github.com/fatih/color"
-// 	"golang.org/x/exp/slog"
-// )
-
-// type PrettyHandlerOptions struct {
-// 	SlogOpts *slog.HandlerOptions
-// }
-
-// type PrettyHandler struct {
-// 	opts PrettyHandlerOptions
-// 	slog.Handler
-// 	l     *stdLog.Logger
-// 	attrs []slog.Attr
-// }
-
-// func (opts PrettyHandlerOptions) NewPrettyHandler(
-// 	out io.Writer,
-// ) *PrettyHandler {
-// 	h := &PrettyHandler{
-// 		Handler: slog.NewJSONHandler(out, opts.SlogOpts),
-// 		l:       stdLog.New(out, "", 0),
-// 	}
-
-// 	return h
-// }
-
-// func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
-// 	level := r.Level.String() + ":"
-
-// 	switch r.Level {
-// 	case slog.LevelDebug:
-// 		level = color.MagentaString(level)
-// 	case slog.LevelInfo:
-// 		level = color.BlueString(level)
-// 	case slog.LevelWarn:
-// 		level = color.YellowString(level)
-// 	case slog.LevelError:
-// 		level = color.RedString(level)
-// 	}
-
-// 	fields := make(map[string]interface{}, r.NumAttrs())
-
-// 	r.Attrs(func(a slog.Attr) bool {
-// 		fields[a.Key] = a.Value.Any()
-
-// 		return true
-// 	})
-
-// 	for _, a := range h.attrs {
-// 		fields[a.Key] = a.Value.Any()
-// 	}
-
-// 	var b []byte
-// 	var err error
-
-// 	if len(fields) > 0 {
-// 		b, err = json.MarshalIndent(fields, "", "  ")
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-
-// 	timeStr := r.Time.Format("[15:05:05.000]")
-// 	msg := color.CyanString(r.Message)
-
-// 	h.l.Println(
-// 		timeStr,
-// 		level,
-// 		msg,
-// 		color.WhiteString(string(b)),
-// 	)
-
-// 	return nil
-// }
-
-// func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-// 	return &PrettyHandler{
-// 		Handler: h.Handler,
-// 		l:       h.l,
-// 		attrs:   attrs,
-// 	}
-// }
-
-// func (h *PrettyHandler) WithGroup(name string) slog.Handler {
-// 	// TODO: implement
-// 	return &PrettyHandler{
-// 		Handler: h.Handler.WithGroup(name),
-// 		l:       h.l,
-// 	}
-// }
+import (
+	"context"
+	"encoding/json"
+	"io"
+	stdLog "log"
+	"log/slog"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type PrettyHandlerOptions struct {
+	SlogOpts *slog.HandlerOptions
+}
+
+// PrettyHandler writes one colored line per record, followed by its
+// attributes as indented JSON. It is meant for reading logs in a terminal
+// during local development, not for machines.
+type PrettyHandler struct {
+	opts PrettyHandlerOptions
+	l    *stdLog.Logger
+
+	// attrs are the attributes added with WithAttrs, each remembering the
+	// groups that were open when it was added.
+	attrs  []groupedAttr
+	groups []string
+}
+
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+func (opts PrettyHandlerOptions) NewPrettyHandler(
+	out io.Writer,
+) *PrettyHandler {
+	h := &PrettyHandler{
+		opts: opts,
+		l:    stdLog.New(out, "", 0),
+	}
+
+	return h
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.SlogOpts != nil && h.opts.SlogOpts.Level != nil {
+		min = h.opts.SlogOpts.Level.Level()
+	}
+
+	return level >= min
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	level := r.Level.String() + ":"
+
+	switch {
+	case r.Level < slog.LevelInfo:
+		level = color.MagentaString(level)
+	case r.Level < slog.LevelWarn:
+		level = color.BlueString(level)
+	case r.Level < slog.LevelError:
+		level = color.YellowString(level)
+	default:
+		level = color.RedString(level)
+	}
+
+	fields := make(map[string]interface{})
+	for _, ga := range h.attrs {
+		addAttr(fields, ga.groups, ga.attr)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.groups, a)
+
+		return true
+	})
+
+	var b []byte
+	var err error
+
+	if len(fields) > 0 {
+		b, err = json.MarshalIndent(fields, "", "  ")
+		if err != nil {
+			return err
+		}
+	}
+
+	msg := color.CyanString(r.Message)
+	line := []interface{}{level, msg, color.WhiteString(string(b))}
+	if !r.Time.IsZero() {
+		line = append([]interface{}{r.Time.Format("[15:04:05.000]")}, line...)
+	}
+
+	h.l.Println(line...)
+
+	return nil
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := h.clone()
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, groupedAttr{groups: h.groups, attr: a})
+	}
+
+	return h2
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+
+	return h2
+}
+
+// clone copies h with slices of its own, so that handlers derived from the
+// same parent never share appended elements.
+func (h *PrettyHandler) clone() *PrettyHandler {
+	return &PrettyHandler{
+		opts:   h.opts,
+		l:      h.l,
+		attrs:  append([]groupedAttr(nil), h.attrs...),
+		groups: append([]string(nil), h.groups...),
+	}
+}
+
+// addAttr puts a into fields under the nested objects named by groups,
+// following the slog.Handler rules: empty attributes and empty groups are
+// dropped, and a group without a key is inlined.
+func addAttr(fields map[string]interface{}, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		members := a.Value.Group()
+		if len(members) == 0 {
+			return
+		}
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, m := range members {
+			addAttr(fields, groups, m)
+		}
+		return
+	}
+
+	for _, g := range groups {
+		sub, ok := fields[g].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			fields[g] = sub
+		}
+		fields = sub
+	}
+	fields[a.Key] = value(a.Value)
+}
+
+// value converts v to something encoding/json renders readably.
+func value(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return x.Error()
+		case json.Marshaler:
+			return x
+		case interface{ String() string }:
+			return x.String()
+		}
+	}
+
+	return v.Any()
+}
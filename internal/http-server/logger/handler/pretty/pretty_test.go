@@ -0,0 +1,156 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func init() {
+	color.NoColor = true
+}
+
+func newTestHandler(buf *bytes.Buffer) *PrettyHandler {
+	return PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{Level: slog.LevelDebug},
+	}.NewPrettyHandler(buf)
+}
+
+// parse splits one record written by the handler into the slog built-in
+// keys and the attribute JSON that follows the message.
+func parse(t *testing.T, line string) map[string]interface{} {
+	t.Helper()
+
+	line = strings.TrimSuffix(line, "\n")
+	m := make(map[string]interface{})
+
+	if strings.HasPrefix(line, "[") {
+		ts, rest, ok := strings.Cut(line[1:], "] ")
+		if !ok {
+			t.Fatalf("no end of time in %q", line)
+		}
+		m[slog.TimeKey] = ts
+		line = rest
+	}
+
+	level, rest, ok := strings.Cut(line, ": ")
+	if !ok {
+		t.Fatalf("no level in %q", line)
+	}
+	m[slog.LevelKey] = level
+
+	msg, fields, _ := strings.Cut(rest, " {\n")
+	m[slog.MessageKey] = strings.TrimSuffix(msg, " ")
+	if fields != "" {
+		if err := json.Unmarshal([]byte("{\n"+fields), &m); err != nil {
+			t.Fatalf("attributes of %q: %v", line, err)
+		}
+	}
+
+	return m
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	slogtest.Run(t, func(*testing.T) slog.Handler {
+		buf.Reset()
+		return newTestHandler(&buf)
+	}, func(t *testing.T) map[string]interface{} {
+		return parse(t, buf.String())
+	})
+}
+
+func TestWithAttrsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(newTestHandler(&buf)).
+		With(slog.String("env", "local")).
+		WithGroup("req").
+		With(slog.String("id", "abc")).
+		WithGroup("db")
+
+	log.Info("done", slog.Int("rows", 3))
+
+	got := parse(t, buf.String())
+	want := map[string]interface{}{
+		"env": "local",
+		"req": map[string]interface{}{
+			"id": "abc",
+			"db": map[string]interface{}{"rows": float64(3)},
+		},
+	}
+
+	for key, value := range want {
+		if !equalJSON(got[key], value) {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestDerivedHandlersDoNotShareAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(newTestHandler(&buf)).With(slog.String("env", "local"))
+
+	a := base.With(slog.String("who", "a"))
+	base.With(slog.String("who", "b"))
+
+	a.Info("hello")
+
+	got := parse(t, buf.String())
+	if got["env"] != "local" || got["who"] != "a" {
+		t.Errorf("attrs = %v, want env=local who=a", got)
+	}
+}
+
+func TestDropsEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(newTestHandler(&buf))
+
+	log.WithGroup("unused").Info("hello",
+		slog.Attr{},
+		slog.Group("empty"),
+		slog.Group("", slog.String("inlined", "yes")),
+	)
+
+	got := parse(t, buf.String())
+	if _, ok := got["unused"]; !ok {
+		t.Fatalf("attrs = %v, want the inlined attr under unused", got)
+	}
+	if want := map[string]interface{}{"inlined": "yes"}; !equalJSON(got["unused"], want) {
+		t.Errorf("unused = %v, want %v", got["unused"], want)
+	}
+
+	buf.Reset()
+	log.WithGroup("unused").Info("bare", slog.Group("empty"))
+
+	if got := buf.String(); strings.Contains(got, "{") || strings.Contains(got, "unused") {
+		t.Errorf("output = %q, want no attributes", got)
+	}
+}
+
+func TestZeroTime(t *testing.T) {
+	var buf bytes.Buffer
+	h := newTestHandler(&buf)
+
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "no time", 0)
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := parse(t, buf.String()); got[slog.TimeKey] != nil {
+		t.Errorf("time = %v, want none for a zero record time", got[slog.TimeKey])
+	}
+}
+
+func equalJSON(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+
+	return bytes.Equal(x, y)
+}
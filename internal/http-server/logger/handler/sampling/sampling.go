@@ -0,0 +1,79 @@
+package sampling
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Options says how many records with the same message get through per
+// Tick: the First ones, then every Thereafter-th (none if it is 0).
+type Options struct {
+	First      int
+	Thereafter int
+	Tick       time.Duration
+}
+
+// Handler thins out repetitive records before passing them to the next
+// handler. Warnings and errors are never dropped. Handlers derived with
+// WithAttrs and WithGroup share their parent's counts, so per-request
+// loggers are sampled together.
+type Handler struct {
+	next slog.Handler
+	opts Options
+	c    *counter
+}
+
+type counter struct {
+	mu     sync.Mutex
+	window time.Time
+	counts map[string]int
+}
+
+func New(next slog.Handler, opts Options) *Handler {
+	return &Handler{
+		next: next,
+		opts: opts,
+		c:    &counter{counts: make(map[string]int)},
+	}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn || h.keep(r.Message, r.Time) {
+		return h.next.Handle(ctx, r)
+	}
+
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{next: h.next.WithAttrs(attrs), opts: h.opts, c: h.c}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), opts: h.opts, c: h.c}
+}
+
+// keep counts one more record with msg and reports whether to log it.
+func (h *Handler) keep(msg string, now time.Time) bool {
+	h.c.mu.Lock()
+	defer h.c.mu.Unlock()
+
+	if now.Sub(h.c.window) >= h.opts.Tick {
+		h.c.window = now
+		clear(h.c.counts)
+	}
+
+	h.c.counts[msg]++
+	n := h.c.counts[msg]
+	if n <= h.opts.First {
+		return true
+	}
+
+	return h.opts.Thereafter > 0 && (n-h.opts.First)%h.opts.Thereafter == 0
+}
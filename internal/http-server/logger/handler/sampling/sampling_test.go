@@ -0,0 +1,112 @@
+package sampling
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// recorder counts the records that got through, by message.
+type recorder struct {
+	counts map[string]int
+}
+
+func (r *recorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (r *recorder) Handle(ctx context.Context, record slog.Record) error {
+	r.counts[record.Message]++
+	return nil
+}
+
+func (r *recorder) WithAttrs([]slog.Attr) slog.Handler { return r }
+func (r *recorder) WithGroup(string) slog.Handler      { return r }
+
+func newHandler(opts Options) (*Handler, *recorder) {
+	rec := &recorder{counts: make(map[string]int)}
+	return New(rec, opts), rec
+}
+
+// send passes n records with msg at level through h, all at time at.
+func send(t *testing.T, h slog.Handler, level slog.Level, msg string, n int, at time.Time) {
+	t.Helper()
+
+	for range n {
+		if err := h.Handle(context.Background(), slog.NewRecord(at, level, msg, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFirstThenEvery(t *testing.T) {
+	h, rec := newHandler(Options{First: 3, Thereafter: 10, Tick: time.Minute})
+	now := time.Now()
+
+	// 3 first, then the 10th and 20th of the 25 after them.
+	send(t, h, slog.LevelInfo, "request completed", 28, now)
+	if got := rec.counts["request completed"]; got != 5 {
+		t.Errorf("%d records kept, want 5", got)
+	}
+}
+
+func TestThereafterZeroDropsTheRest(t *testing.T) {
+	h, rec := newHandler(Options{First: 2, Tick: time.Minute})
+
+	send(t, h, slog.LevelInfo, "request completed", 50, time.Now())
+	if got := rec.counts["request completed"]; got != 2 {
+		t.Errorf("%d records kept, want 2", got)
+	}
+}
+
+func TestMessagesCountedApart(t *testing.T) {
+	h, rec := newHandler(Options{First: 1, Tick: time.Minute})
+	now := time.Now()
+
+	send(t, h, slog.LevelInfo, "request completed", 5, now)
+	send(t, h, slog.LevelInfo, "movie created", 5, now)
+	send(t, h, slog.LevelDebug, "cache miss", 5, now)
+
+	for _, msg := range []string{"request completed", "movie created", "cache miss"} {
+		if rec.counts[msg] != 1 {
+			t.Errorf("%q: %d records kept, want 1", msg, rec.counts[msg])
+		}
+	}
+}
+
+func TestWarningsNeverDropped(t *testing.T) {
+	h, rec := newHandler(Options{First: 1, Tick: time.Minute})
+	now := time.Now()
+
+	send(t, h, slog.LevelWarn, "request completed", 4, now)
+	send(t, h, slog.LevelError, "request completed", 4, now)
+	if got := rec.counts["request completed"]; got != 8 {
+		t.Errorf("%d warnings and errors kept, want all 8", got)
+	}
+}
+
+func TestTickResetsCounts(t *testing.T) {
+	h, rec := newHandler(Options{First: 2, Tick: time.Second})
+	now := time.Now()
+
+	send(t, h, slog.LevelInfo, "request completed", 5, now)
+	send(t, h, slog.LevelInfo, "request completed", 5, now.Add(500*time.Millisecond))
+	send(t, h, slog.LevelInfo, "request completed", 5, now.Add(time.Second))
+	if got := rec.counts["request completed"]; got != 4 {
+		t.Errorf("%d records kept over two ticks, want 4", got)
+	}
+}
+
+func TestDerivedHandlersShareCounts(t *testing.T) {
+	h, rec := newHandler(Options{First: 1, Tick: time.Minute})
+	now := time.Now()
+
+	// Per-request loggers are derived with attributes; they must not each
+	// get their own First records.
+	for i := range 5 {
+		derived := h.WithAttrs([]slog.Attr{slog.Int("request", i)}).WithGroup("http")
+		send(t, derived, slog.LevelInfo, "request completed", 1, now)
+	}
+	if got := rec.counts["request completed"]; got != 1 {
+		t.Errorf("%d records kept across derived handlers, want 1", got)
+	}
+}
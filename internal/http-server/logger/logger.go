@@ -1,36 +1,83 @@
-package handler
+package logger
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		log := log.With(
-			slog.String("component", "middleware/logger"),
-		)
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx carrying log.
+func WithContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
 
-		log.Info("logger middleware enabled")
+// FromContext returns the request logger New stored in ctx, or fallback
+// when there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+
+	return fallback
+}
+
+// New logs every request once it has been served, with its status, the
+// number of bytes written and how long it took. The request's logger,
+// already carrying the method, path and request id, is put in the context
+// for handlers to pick up with FromContext, along with the trace and span
+// ids when the request is traced. It should run after middleware.RequestID
+// and tracing.Middleware.
+//
+// The "request completed" record goes to completed, which may be a sampled
+// logger, while the one in the context is built on log, so handler records
+// are never sampled away. A nil completed means log.
+func New(log, completed *slog.Logger) func(next http.Handler) http.Handler {
+	if completed == nil {
+		completed = log
+	}
+
+	return func(next http.Handler) http.Handler {
+		log.Info("logger middleware enabled", slog.String("component", "middleware/logger"))
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			entry := log.With(
+			attrs := []interface{}{
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
-			)
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			}
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				attrs = append(attrs,
+					slog.String("trace_id", sc.TraceID().String()),
+					slog.String("span_id", sc.SpanID().String()),
+				)
+			}
+			entry := log.With(attrs...)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			t1 := time.Now()
 			defer func() {
-				entry.Info("request completed",
-					slog.Int("Code", r.Response.StatusCode),
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				completed.With(attrs...).Info("request completed",
+					slog.String("component", "middleware/logger"),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
 					slog.String("duration", time.Since(t1).String()),
 				)
 			}()
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(ww, r.WithContext(WithContext(r.Context(), entry)))
 		}
 
 		return http.HandlerFunc(fn)
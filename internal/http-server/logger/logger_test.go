@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/http-server/logger/handler/sampling"
+)
+
+func TestSamplesOnlyCompletedRecords(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	sampled := slog.New(sampling.New(log.Handler(), sampling.Options{First: 1, Tick: time.Hour}))
+
+	handler := middleware.RequestID(New(log, sampled)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context(), nil).Info("handled")
+		w.WriteHeader(http.StatusTeapot)
+	})))
+
+	const requests = 3
+	for i := 0; i < requests; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/movies", nil))
+	}
+
+	counts := make(map[string]int)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		msg := record["msg"].(string)
+		counts[msg]++
+
+		if msg == "logger middleware enabled" {
+			continue
+		}
+		if record["path"] != "/movies" || record["request_id"] == "" || record["request_id"] == nil {
+			t.Errorf("%q lacks the request attributes: %v", msg, record)
+		}
+		if msg == "request completed" && record["status"] != float64(http.StatusTeapot) {
+			t.Errorf("status = %v, want %d", record["status"], http.StatusTeapot)
+		}
+	}
+
+	if counts["handled"] != requests {
+		t.Errorf("%d handler records, want %d: they must not be sampled", counts["handled"], requests)
+	}
+	if counts["request completed"] != 1 {
+		t.Errorf("%d request completed records, want 1", counts["request completed"])
+	}
+}
+
+func TestNilCompletedLogsEveryRequest(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := New(log, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	if got := bytes.Count(buf.Bytes(), []byte(`"msg":"request completed"`)); got != 2 {
+		t.Errorf("%d request completed records, want 2", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Package response writes the JSON error envelope every handler answers
+// failures with.
+package response
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/validation"
+)
+
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidJSON      = "invalid_json"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeValidation       = "validation_failed"
+	CodeInternal         = "internal_error"
+)
+
+var (
+	ErrUnauthorized = errors.New("authentication required")
+	ErrForbidden    = errors.New("not allowed for this account")
+)
+
+// ErrorResponse is the body of every 4xx and 5xx answer.
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// requestError is a client mistake found by a handler itself, before any
+// storage call: a malformed id, query parameter or body.
+type requestError struct {
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// BadRequest reports a malformed path or query parameter.
+func BadRequest(message string) error {
+	return &requestError{code: CodeBadRequest, message: message}
+}
+
+// InvalidJSON reports a request body that could not be decoded.
+func InvalidJSON(err error) error {
+	return &requestError{code: CodeInvalidJSON, message: "invalid request body: " + err.Error()}
+}
+
+// statuses maps access and storage errors onto HTTP statuses. The message sent to the
+// client is the sentinel's own text, never the wrapped chain, which names
+// internal functions.
+var statuses = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized},
+	{ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{storage.ErrMovieNotFound, http.StatusNotFound, CodeNotFound},
+	{storage.ErrActorNotFound, http.StatusNotFound, CodeNotFound},
+	{storage.ErrUserNotFound, http.StatusNotFound, CodeNotFound},
+	{storage.ErrAPIKeyNotFound, http.StatusNotFound, CodeNotFound},
+	{storage.ErrFilmExists, http.StatusConflict, CodeConflict},
+	{storage.ErrActorExists, http.StatusConflict, CodeConflict},
+	{storage.ErrRuleExists, http.StatusConflict, CodeConflict},
+	{storage.ErrUserExists, http.StatusConflict, CodeConflict},
+	{storage.ErrAPIKeyExists, http.StatusConflict, CodeConflict},
+	{storage.ErrInvalidCursor, http.StatusBadRequest, CodeBadRequest},
+	{storage.ErrInvalidSort, http.StatusBadRequest, CodeBadRequest},
+	{storage.ErrInvalidFilter, http.StatusBadRequest, CodeBadRequest},
+}
+
+// Error answers r with the envelope for err. Anything that isn't a known
+// client or storage error is logged and reported as a bare 500.
+func Error(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	var reqErr *requestError
+	var validationErrs validation.Errors
+
+	switch {
+	case errors.As(err, &reqErr):
+		write(w, r, http.StatusBadRequest, reqErr.code, reqErr.message, nil)
+		return
+	case errors.As(err, &validationErrs):
+		write(w, r, http.StatusUnprocessableEntity, CodeValidation, "request validation failed", validationErrs)
+		return
+	}
+
+	for _, s := range statuses {
+		if errors.Is(err, s.err) {
+			write(w, r, s.status, s.code, s.err.Error(), nil)
+			return
+		}
+	}
+
+	log.Error("request failed", slog.Any("error", err))
+	write(w, r, http.StatusInternalServerError, CodeInternal, "internal server error", nil)
+}
+
+// NotFound answers requests no route matches.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	write(w, r, http.StatusNotFound, CodeNotFound, "no such endpoint", nil)
+}
+
+// MethodNotAllowed answers requests whose path exists but not for r.Method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	write(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed", nil)
+}
+
+func write(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	render.Status(r, status)
+	render.JSON(w, r, ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+}
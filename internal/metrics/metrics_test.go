@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/movies/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/actors", func(r chi.Router) {
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+		r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/movies/1"},
+		{http.MethodGet, "/movies/2"},
+		{http.MethodGet, "/movies/abc"},
+		{http.MethodPost, "/actors"},
+		{http.MethodDelete, "/actors/7"},
+		{http.MethodGet, "/nope/1"},
+		{http.MethodGet, "/nope/2"},
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	tests := []struct {
+		route, method, status string
+		want                  float64
+	}{
+		{"/movies/{id}", http.MethodGet, "200", 3},
+		{"/actors", http.MethodPost, "201", 1},
+		{"/actors/{id}", http.MethodDelete, "404", 1},
+		{"unmatched", http.MethodGet, "404", 2},
+	}
+
+	// One series per route, never one per path.
+	if n := testutil.CollectAndCount(m.requests); n != len(tests) {
+		t.Errorf("%d request series, want %d", n, len(tests))
+	}
+	if n := testutil.CollectAndCount(m.duration); n != len(tests) {
+		t.Errorf("%d duration series, want %d", n, len(tests))
+	}
+
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tt.route, tt.method, tt.status)); got != tt.want {
+			t.Errorf("requests{route=%q,method=%q,status=%q} = %v, want %v", tt.route, tt.method, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Handle("/metrics", m.Handler())
+	r.Get("/movies/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/movies/42", nil))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`cinema_http_requests_total{method="GET",route="/movies/{id}",status="200"} 1`,
+		`cinema_http_request_duration_seconds_count{method="GET",route="/movies/{id}",status="200"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("scrape has no %s", want)
+		}
+	}
+	if strings.Contains(string(body), "/movies/42") {
+		t.Errorf("scrape has a raw path")
+	}
+}
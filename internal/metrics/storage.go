@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+// Storage times the listing, fragment search and write calls of a
+// storage.Repository; every other call goes straight through.
+type Storage struct {
+	storage.Repository
+
+	m       *Metrics
+	backend string
+}
+
+// InstrumentStorage wraps s, labeling its calls with backend.
+func (m *Metrics) InstrumentStorage(backend string, s storage.Repository) *Storage {
+	return &Storage{Repository: s, m: m, backend: backend}
+}
+
+// Unwrap returns the wrapped repository.
+func (s *Storage) Unwrap() storage.Repository {
+	return s.Repository
+}
+
+// Close closes the wrapped repository if it holds resources.
+func (s *Storage) Close() error {
+	if closer, ok := s.Repository.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// observe records a call that started at start and failed with *err, if
+// it did. It is meant to be deferred with a pointer to the named result.
+func (s *Storage) observe(query string, start time.Time, err *error) {
+	s.m.ObserveQuery(s.backend, query, start, *err)
+}
+
+func (s *Storage) GetMoviesSorted(ctx context.Context, filter storage.MovieFilter, sortBy string, page storage.Page) (movies []models.Movie, next string, err error) {
+	defer s.observe("GetMoviesSorted", time.Now(), &err)
+	return s.Repository.GetMoviesSorted(ctx, filter, sortBy, page)
+}
+
+func (s *Storage) GetMovieByFragment(ctx context.Context, fragmentType string, fragment string, fuzzy bool, limit int) (results []models.SearchResult, err error) {
+	defer s.observe("GetMovieByFragment", time.Now(), &err)
+	return s.Repository.GetMovieByFragment(ctx, fragmentType, fragment, fuzzy, limit)
+}
+
+func (s *Storage) CreateMovie(ctx context.Context, title string, description string, date time.Time, rating int8) (id int64, err error) {
+	defer s.observe("CreateMovie", time.Now(), &err)
+	return s.Repository.CreateMovie(ctx, title, description, date, rating)
+}
+
+func (s *Storage) UpdateMovie(ctx context.Context, filmId int64, patch storage.MoviePatch) (movie models.Movie, err error) {
+	defer s.observe("UpdateMovie", time.Now(), &err)
+	return s.Repository.UpdateMovie(ctx, filmId, patch)
+}
+
+func (s *Storage) DeliteMovie(ctx context.Context, filmId int) (err error) {
+	defer s.observe("DeliteMovie", time.Now(), &err)
+	return s.Repository.DeliteMovie(ctx, filmId)
+}
+
+func (s *Storage) CreateActor(ctx context.Context, name string, gender string, birth time.Time) (id int64, err error) {
+	defer s.observe("CreateActor", time.Now(), &err)
+	return s.Repository.CreateActor(ctx, name, gender, birth)
+}
+
+func (s *Storage) UpdateActor(ctx context.Context, actorId int64, patch storage.ActorPatch) (actor models.Actor, err error) {
+	defer s.observe("UpdateActor", time.Now(), &err)
+	return s.Repository.UpdateActor(ctx, actorId, patch)
+}
+
+func (s *Storage) DeleteActor(ctx context.Context, actorId int64) (err error) {
+	defer s.observe("DeleteActor", time.Now(), &err)
+	return s.Repository.DeleteActor(ctx, actorId)
+}
+
+func (s *Storage) CreateRule(ctx context.Context, movieId int, cast []models.CastEntry) (err error) {
+	defer s.observe("CreateRule", time.Now(), &err)
+	return s.Repository.CreateRule(ctx, movieId, cast)
+}
+
+func (s *Storage) ReplaceRules(ctx context.Context, movieId int64, cast []models.CastEntry) (err error) {
+	defer s.observe("ReplaceRules", time.Now(), &err)
+	return s.Repository.ReplaceRules(ctx, movieId, cast)
+}
+
+func (s *Storage) DeleteRules(ctx context.Context, movieId int64) (err error) {
+	defer s.observe("DeleteRules", time.Now(), &err)
+	return s.Repository.DeleteRules(ctx, movieId)
+}
+
+func (s *Storage) CreateUser(ctx context.Context, username string, passwordHash string, role models.UserRole) (id int64, err error) {
+	defer s.observe("CreateUser", time.Now(), &err)
+	return s.Repository.CreateUser(ctx, username, passwordHash, role)
+}
+
+func (s *Storage) CreateRefreshToken(ctx context.Context, tokenId string, userId int64, expiresAt time.Time) (err error) {
+	defer s.observe("CreateRefreshToken", time.Now(), &err)
+	return s.Repository.CreateRefreshToken(ctx, tokenId, userId, expiresAt)
+}
+
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenId string) (err error) {
+	defer s.observe("UseRefreshToken", time.Now(), &err)
+	return s.Repository.UseRefreshToken(ctx, tokenId)
+}
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key models.APIKey) (id int64, err error) {
+	defer s.observe("CreateAPIKey", time.Now(), &err)
+	return s.Repository.CreateAPIKey(ctx, key)
+}
+
+func (s *Storage) DeleteAPIKey(ctx context.Context, keyId int64) (err error) {
+	defer s.observe("DeleteAPIKey", time.Now(), &err)
+	return s.Repository.DeleteAPIKey(ctx, keyId)
+}
+
+func (s *Storage) TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) (err error) {
+	defer s.observe("TouchAPIKey", time.Now(), &err)
+	return s.Repository.TouchAPIKey(ctx, keyId, usedAt)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/memory"
+)
+
+// calls returns how many storage calls m timed with the given labels.
+func calls(t *testing.T, m *Metrics, backend, query, outcome string) uint64 {
+	t.Helper()
+
+	var metric dto.Metric
+	if err := m.queries.WithLabelValues(backend, query, outcome).(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentStorage(t *testing.T) {
+	m := New()
+	inner := memory.New()
+	s := m.InstrumentStorage("memory", inner)
+	ctx := context.Background()
+
+	if s.Unwrap() != storage.Repository(inner) {
+		t.Errorf("Unwrap doesn't return the wrapped repository")
+	}
+
+	id, err := s.CreateMovie(ctx, "Alien", "", time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.GetMoviesSorted(ctx, storage.MovieFilter{}, "", storage.Page{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeliteMovie(ctx, int(id)+1); !errors.Is(err, storage.ErrMovieNotFound) {
+		t.Fatalf("DeliteMovie: err = %v, want %v", err, storage.ErrMovieNotFound)
+	}
+	if err := s.DeliteMovie(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads by id go straight through, untimed.
+	if _, err := s.GetMovie(ctx, id); !errors.Is(err, storage.ErrMovieNotFound) {
+		t.Fatalf("GetMovie: err = %v, want %v", err, storage.ErrMovieNotFound)
+	}
+
+	tests := []struct {
+		query, outcome string
+		want           uint64
+	}{
+		{"CreateMovie", "ok", 1},
+		{"GetMoviesSorted", "ok", 1},
+		{"DeliteMovie", "ok", 1},
+		{"DeliteMovie", "error", 1},
+	}
+
+	if n := testutil.CollectAndCount(m.queries); n != len(tests) {
+		t.Errorf("%d query series, want %d", n, len(tests))
+	}
+	for _, tt := range tests {
+		if got := calls(t, m, "memory", tt.query, tt.outcome); got != tt.want {
+			t.Errorf("%s %s: %d calls, want %d", tt.query, tt.outcome, got, tt.want)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	s := New().InstrumentStorage("memory", memory.New())
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Close of a repository without resources: %v", err)
+	}
+}
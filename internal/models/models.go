@@ -3,25 +3,150 @@ package models
 import "time"
 
 type Movie struct {
-	Id          int64
-	Title       string
-	Description string
-	Date        time.Time
-	Rating      int
-	Actors      []Actor
+	Id          int64        `json:"id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Date        time.Time    `json:"date"`
+	Rating      int          `json:"rating"`
+	Actors      []CastMember `json:"actors"`
 }
 
 type Actor struct {
-	Id     int64
-	Name   string
-	Gender string
-	Birth  time.Time
-	Movies []string
-}
-
-type SerchMovieParams struct {
-	Sort         bool   `json: "sort`
-	SortType     string `json: "type-sort"`
-	FragmentType string `json: "type-fragment"`
-	Fragments    string `json: "fragments"`
+	Id     int64          `json:"id"`
+	Name   string         `json:"name"`
+	Gender string         `json:"gender"`
+	Birth  time.Time      `json:"birth"`
+	Movies []MovieSummary `json:"movies"`
+}
+
+// CastMember is an actor as listed in a movie's cast: who they are and the
+// part they play, without their own filmography.
+type CastMember struct {
+	Id     int64  `json:"id"`
+	Name   string `json:"name"`
+	Gender string `json:"gender"`
+	Role   Role   `json:"role"`
+}
+
+// MovieSummary is an entry in an actor's filmography.
+type MovieSummary struct {
+	Id     int64     `json:"id"`
+	Title  string    `json:"title"`
+	Date   time.Time `json:"date"`
+	Rating int       `json:"rating"`
+	Role   Role      `json:"role"`
+}
+
+type CreditType string
+
+const (
+	CreditLead       CreditType = "lead"
+	CreditSupporting CreditType = "supporting"
+	CreditCameo      CreditType = "cameo"
+	CreditVoice      CreditType = "voice"
+)
+
+func (c CreditType) Valid() bool {
+	switch c {
+	case CreditLead, CreditSupporting, CreditCameo, CreditVoice:
+		return true
+	}
+
+	return false
+}
+
+// Role describes the part an actor plays in a movie.
+type Role struct {
+	Character    string     `json:"character"`
+	BillingOrder int        `json:"billing_order"`
+	CreditType   CreditType `json:"credit_type"`
+}
+
+// CastEntry links an actor to a movie in a given role.
+type CastEntry struct {
+	ActorId int64 `json:"actor_id"`
+	Role
+}
+
+// SearchResult is a movie found by a search. For full-text search Snippet
+// is HTML: a fragment of the best matching field, escaped, with hits
+// wrapped in <mark></mark>. For a fragment search it is the matched title or
+// actor name as plain text. A higher Score means a better match.
+type SearchResult struct {
+	Movie   Movie   `json:"movie"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// UserRole is what an API account may do: a user reads and searches, an
+// admin may also create, update and delete.
+type UserRole string
+
+const (
+	RoleUser  UserRole = "user"
+	RoleAdmin UserRole = "admin"
+)
+
+func (r UserRole) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
+// Allows reports whether r grants everything required does.
+func (r UserRole) Allows(required UserRole) bool {
+	return r == RoleAdmin || r == required
+}
+
+// User is an API account. PasswordHash is a bcrypt hash and never leaves
+// the server.
+type User struct {
+	Id           int64    `json:"id"`
+	Username     string   `json:"username"`
+	Role         UserRole `json:"role"`
+	PasswordHash string   `json:"-"`
+}
+
+// Scope is one permission an API key or account can hold.
+type Scope string
+
+const (
+	// ScopeMoviesRead covers every read and search endpoint.
+	ScopeMoviesRead  Scope = "movies:read"
+	ScopeMoviesWrite Scope = "movies:write"
+	ScopeActorsWrite Scope = "actors:write"
+)
+
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeMoviesRead, ScopeMoviesWrite, ScopeActorsWrite:
+		return true
+	}
+
+	return false
+}
+
+// Scopes lists what an account with role r may do.
+func (r UserRole) Scopes() []Scope {
+	if r == RoleAdmin {
+		return []Scope{ScopeMoviesRead, ScopeMoviesWrite, ScopeActorsWrite}
+	}
+
+	return []Scope{ScopeMoviesRead}
+}
+
+// APIKey lets a service call the API without logging in. Only a hash of
+// the key is stored; Prefix is the public part used to look it up.
+type APIKey struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Hash       string     `json:"-"`
+}
+
+// Expired reports whether the key can no longer be used at now.
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
 }
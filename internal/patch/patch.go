@@ -0,0 +1,40 @@
+// Package patch decodes JSON merge patch (RFC 7396) request bodies.
+package patch
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Field is one member of a merge patch. Set is false when the member was
+// absent and Null when it was given as null. Invalid means the value did
+// not decode into T; it is recorded rather than failing the whole body so
+// that the handler can report it against the field.
+type Field[T any] struct {
+	Value   T
+	Set     bool
+	Null    bool
+	Invalid bool
+}
+
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Null = true
+		return nil
+	}
+	if err := json.Unmarshal(data, &f.Value); err != nil {
+		f.Invalid = true
+	}
+
+	return nil
+}
+
+// Decode reads a merge patch document into dst, a struct of Fields.
+// Members dst has no Field for are an error.
+func Decode(r io.Reader, dst interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(dst)
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+// JoinScopes renders scopes as the space separated list kept in the
+// api_keys table.
+func JoinScopes(scopes []models.Scope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		parts = append(parts, string(scope))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// SplitScopes is the inverse of JoinScopes.
+func SplitScopes(s string) []models.Scope {
+	scopes := []models.Scope{}
+	for _, part := range strings.Fields(s) {
+		scopes = append(scopes, models.Scope(part))
+	}
+
+	return scopes
+}
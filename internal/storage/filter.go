@@ -0,0 +1,17 @@
+package storage
+
+import "errors"
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// MovieFilter narrows a movie listing. Unset fields are ignored; every set
+// field must match. Title and Actor are case-insensitive substring matches,
+// the ranges are inclusive.
+type MovieFilter struct {
+	Title     string
+	Actor     string
+	YearFrom  *int
+	YearTo    *int
+	RatingMin *int
+	RatingMax *int
+}
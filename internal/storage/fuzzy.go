@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+// cyrillic maps Russian letters to their common Latin spelling, so that
+// "Кино" and "Kino" fold to the same string.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Transliterate lower-cases s and spells Cyrillic letters in Latin.
+func Transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if latin, ok := cyrillic[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// MatchScore reports whether fragment matches text and how well, from 0 to
+// 1. An exact match is a case-insensitive substring and always scores 1. A
+// fuzzy match compares both transliterated, word by word: every word of
+// fragment must be a prefix of, or within a few typos of, some word of text
+// that starts with the same letter.
+func MatchScore(fragment, text string, fuzzy bool) (float64, bool) {
+	if !fuzzy {
+		return 1, strings.Contains(strings.ToLower(text), strings.ToLower(fragment))
+	}
+
+	terms := SearchTerms(Transliterate(fragment))
+	words := SearchTerms(Transliterate(text))
+	if len(terms) == 0 {
+		return 0, false
+	}
+
+	var total float64
+	for _, term := range terms {
+		best := 0.0
+		for _, word := range words {
+			if score := wordScore(term, word); score > best {
+				best = score
+			}
+		}
+		if best == 0 {
+			return 0, false
+		}
+		total += best
+	}
+
+	return total / float64(len(terms)), true
+}
+
+// wordScore compares one search term with one word of text. A whole word
+// scores 1 and a prefix slightly less; otherwise the term may be a few
+// edits away from the word or from its beginning, but never in its first
+// letter, so that FuzzyInitials can narrow the candidates down.
+func wordScore(term, word string) float64 {
+	switch {
+	case term == word:
+		return 1
+	case strings.HasPrefix(word, term):
+		return 0.9
+	}
+
+	t, w := []rune(term), []rune(word)
+	allowed := maxEdits(len(t))
+	if allowed == 0 || len(w) == 0 || t[0] != w[0] {
+		return 0
+	}
+
+	distance := editDistance(t, w)
+	length := max(len(t), len(w))
+	if len(w) > len(t) {
+		if prefix := editDistance(t, w[:len(t)]); prefix < distance {
+			distance, length = prefix, len(t)
+		}
+	}
+	if distance > allowed {
+		return 0
+	}
+
+	return 0.8 * (1 - float64(distance)/float64(length))
+}
+
+// FuzzyInitials returns, for each word of fragment, the letters a word of
+// untransliterated text may start with to match it in fuzzy mode: the
+// upper- and lower-case first letter, and every Cyrillic letter spelled
+// with it. Backends use them to pre-filter rows before MatchScore.
+func FuzzyInitials(fragment string) []string {
+	terms := SearchTerms(Transliterate(fragment))
+
+	initials := make([]string, 0, len(terms))
+	for _, term := range terms {
+		first := []rune(term)[0]
+
+		letters := []rune{first, unicode.ToUpper(first)}
+		for letter, latin := range cyrillic {
+			if latin != "" && []rune(latin)[0] == first {
+				letters = append(letters, letter, unicode.ToUpper(letter))
+			}
+		}
+
+		slices.Sort(letters)
+		initials = append(initials, string(slices.Compact(letters)))
+	}
+
+	return initials
+}
+
+// maxEdits is how many typos a term of n letters tolerates. Short terms
+// must match exactly, or nearly every word would match them.
+func maxEdits(n int) int {
+	switch {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	}
+
+	return 2
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps
+// of two adjacent letters needed to turn a into b.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+// FragmentMatches collects the movies matching a fragment search, keeping
+// the best scoring text for each movie.
+type FragmentMatches struct {
+	fragment string
+	fuzzy    bool
+	index    map[int64]int
+	results  []models.SearchResult
+}
+
+func NewFragmentMatches(fragment string, fuzzy bool) *FragmentMatches {
+	return &FragmentMatches{
+		fragment: fragment,
+		fuzzy:    fuzzy,
+		index:    make(map[int64]int),
+		results:  []models.SearchResult{},
+	}
+}
+
+// Add scores text, a title or an actor name of movie, against the fragment.
+func (m *FragmentMatches) Add(movie models.Movie, text string) {
+	score, ok := MatchScore(m.fragment, text, m.fuzzy)
+	if !ok {
+		return
+	}
+
+	if i, seen := m.index[movie.Id]; seen {
+		if score > m.results[i].Score {
+			m.results[i].Snippet, m.results[i].Score = text, score
+		}
+		return
+	}
+
+	m.index[movie.Id] = len(m.results)
+	m.results = append(m.results, models.SearchResult{Movie: movie, Snippet: text, Score: score})
+}
+
+// Results returns at most limit matches, best first.
+func (m *FragmentMatches) Results(limit int) []models.SearchResult {
+	sort.SliceStable(m.results, func(i, j int) bool {
+		if m.results[i].Score != m.results[j].Score {
+			return m.results[i].Score > m.results[j].Score
+		}
+		return m.results[i].Movie.Id < m.results[j].Movie.Id
+	})
+
+	if len(m.results) > limit {
+		return m.results[:limit]
+	}
+
+	return m.results
+}
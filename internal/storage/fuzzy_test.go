@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyInitials(t *testing.T) {
+	got := FuzzyInitials("kino, Юность 1984")
+	want := []string{"KkКХкх", "YyЙЫЮЯйыюя", "1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("FuzzyInitials = %q, want %q", got, want)
+	}
+
+	if got := FuzzyInitials(" - "); len(got) != 0 {
+		t.Errorf("FuzzyInitials without words = %q, want none", got)
+	}
+}
+
+func TestMatchScoreFuzzy(t *testing.T) {
+	tests := []struct {
+		fragment string
+		text     string
+		want     bool
+	}{
+		{"alien", "Alien", true},
+		{"ali", "Alien", true},
+		{"alein", "Alien", true},
+		{"aliens", "Alien", true},
+		// A typo in the first letter is not tolerated: backends pre-filter
+		// on each term's initials.
+		{"ilien", "Alien", false},
+		{"hlien", "Alien", false},
+		{"lien", "Alien", false},
+		{"alien", "Ripley: Alien", true},
+		{"kin dza", "Кин-дза-дза!", true},
+		{"хор", "Khor", true},
+		{"alien matrix", "Alien", false},
+		{"ale", "Alien", false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := MatchScore(tt.fragment, tt.text, true); ok != tt.want {
+			t.Errorf("MatchScore(%q, %q) = %v, want %v", tt.fragment, tt.text, ok, tt.want)
+		}
+	}
+}
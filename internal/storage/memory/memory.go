@@ -0,0 +1,730 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+// Storage keeps movies, actors and cast links in process memory. It mirrors
+// the behaviour of sqlite.Storage and is meant for tests and demo instances.
+type Storage struct {
+	mu sync.RWMutex
+
+	movies map[int64]models.Movie
+	actors map[int64]models.Actor
+	rules  []rule
+	users  map[string]models.User
+	keys   map[int64]models.APIKey
+
+	// refresh maps unused refresh token ids to their expiry.
+	refresh map[string]time.Time
+
+	lastMovieId  int64
+	lastActorId  int64
+	lastUserId   int64
+	lastAPIKeyId int64
+}
+
+type rule struct {
+	movieId int64
+	actorId int64
+	role    models.Role
+}
+
+var _ storage.Repository = (*Storage)(nil)
+
+func New() *Storage {
+	return &Storage{
+		movies: make(map[int64]models.Movie),
+		actors: make(map[int64]models.Actor),
+		users:  make(map[string]models.User),
+		keys:   make(map[int64]models.APIKey),
+
+		refresh: make(map[string]time.Time),
+	}
+}
+
+// Actor
+func (s *Storage) CreateActor(ctx context.Context, name string, gender string, birth time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastActorId++
+	s.actors[s.lastActorId] = models.Actor{
+		Id:     s.lastActorId,
+		Name:   name,
+		Gender: gender,
+		Birth:  birth,
+	}
+
+	return s.lastActorId, nil
+}
+
+func (s *Storage) UpdateActor(ctx context.Context, actorId int64, patch storage.ActorPatch) (models.Actor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actor, ok := s.actors[actorId]
+	if !ok {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.memory.UpdateActor", storage.ErrActorNotFound)
+	}
+
+	if patch.Name != nil {
+		actor.Name = *patch.Name
+	}
+	if patch.Gender != nil {
+		actor.Gender = *patch.Gender
+	}
+	if patch.Birth != nil {
+		actor.Birth = *patch.Birth
+	}
+
+	s.actors[actor.Id] = actor
+
+	actor.Movies = s.filmographyOf(actor.Id)
+
+	return actor, nil
+}
+
+func (s *Storage) DeleteActor(ctx context.Context, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.actors[actorId]; !ok {
+		return fmt.Errorf("%s, %w", "storage.memory.DeleteActor", storage.ErrActorNotFound)
+	}
+	delete(s.actors, actorId)
+	s.removeRules(func(r rule) bool { return r.actorId == actorId })
+
+	return nil
+}
+
+func (s *Storage) GetActor(ctx context.Context, actorId int64) (models.Actor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	actor, ok := s.actors[actorId]
+	if !ok {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.memory.GetActor", storage.ErrActorNotFound)
+	}
+
+	actor.Movies = s.filmographyOf(actorId)
+
+	return actor, nil
+}
+
+// GetActors returns one page of actors ordered by id with their
+// filmography, plus the cursor of the next page ("" on the last one).
+func (s *Storage) GetActors(ctx context.Context, page storage.Page) ([]models.Actor, string, error) {
+	var afterId int64
+	if page.Cursor != "" {
+		cursor, err := storage.DecodeActorCursor(page.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.memory.GetActors.Cursor", err)
+		}
+		afterId = cursor.Id
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	actors := make([]models.Actor, 0, len(s.actors))
+	for _, actor := range s.actors {
+		if actor.Id <= afterId {
+			continue
+		}
+		actor.Movies = s.filmographyOf(actor.Id)
+		actors = append(actors, actor)
+	}
+
+	sort.Slice(actors, func(i, j int) bool { return actors[i].Id < actors[j].Id })
+
+	var next string
+	if size := page.Size(); len(actors) > size {
+		actors = actors[:size]
+		next = storage.ActorCursor(actors[size-1]).Encode()
+	}
+
+	return actors, next, nil
+}
+
+//Movie
+
+func (s *Storage) CreateMovie(ctx context.Context, title string, description string, date time.Time, rating int8) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.titleTaken(title, 0) {
+		return 0, fmt.Errorf("%s, %w", "storage.memory.CreateMovie", storage.ErrFilmExists)
+	}
+
+	s.lastMovieId++
+	s.movies[s.lastMovieId] = models.Movie{
+		Id:          s.lastMovieId,
+		Title:       title,
+		Description: description,
+		Date:        date,
+		Rating:      int(rating),
+	}
+
+	return s.lastMovieId, nil
+}
+
+func (s *Storage) UpdateMovie(ctx context.Context, filmId int64, patch storage.MoviePatch) (models.Movie, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movie, ok := s.movies[filmId]
+	if !ok {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.memory.UpdateMovie", storage.ErrMovieNotFound)
+	}
+
+	if patch.Title != nil {
+		movie.Title = *patch.Title
+	}
+	if patch.Description != nil {
+		movie.Description = *patch.Description
+	}
+	if patch.Date != nil {
+		movie.Date = *patch.Date
+	}
+	if patch.Rating != nil {
+		movie.Rating = int(*patch.Rating)
+	}
+
+	if s.titleTaken(movie.Title, movie.Id) {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.memory.UpdateMovie", storage.ErrFilmExists)
+	}
+
+	s.movies[movie.Id] = movie
+
+	movie.Actors = s.castOf(movie.Id)
+
+	return movie, nil
+}
+
+func (s *Storage) DeliteMovie(ctx context.Context, filmId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.movies[int64(filmId)]; !ok {
+		return fmt.Errorf("%s, %w", "storage.memory.DeliteMovie", storage.ErrMovieNotFound)
+	}
+	delete(s.movies, int64(filmId))
+	s.removeRules(func(r rule) bool { return r.movieId == int64(filmId) })
+
+	return nil
+}
+
+func (s *Storage) GetMovie(ctx context.Context, filmId int64) (models.Movie, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	movie, ok := s.movies[filmId]
+	if !ok {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.memory.GetMovie", storage.ErrMovieNotFound)
+	}
+
+	movie.Actors = s.castOf(filmId)
+
+	return movie, nil
+}
+
+func (s *Storage) GetMoviesSorted(ctx context.Context, filter storage.MovieFilter, sortBy string, page storage.Page) ([]models.Movie, string, error) {
+	order, err := storage.ParseMovieOrder(sortBy)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.memory.GetMovies.Order", err)
+	}
+
+	var after *models.Movie
+	if page.Cursor != "" {
+		cursor, err := storage.DecodeCursor(page.Cursor, order.String())
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.memory.GetMovies.Cursor", err)
+		}
+
+		after, err = cursorMovie(order, cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.memory.GetMovies.Cursor", err)
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	movies := make([]models.Movie, 0, len(s.movies))
+	for _, movie := range s.movies {
+		if after != nil && !movieLess(order, *after, movie) {
+			continue
+		}
+		if !s.matches(filter, movie) {
+			continue
+		}
+		movie.Actors = s.castOf(movie.Id)
+		movies = append(movies, movie)
+	}
+
+	sort.Slice(movies, func(i, j int) bool { return movieLess(order, movies[i], movies[j]) })
+
+	var next string
+	if size := page.Size(); len(movies) > size {
+		movies = movies[:size]
+		next = storage.MovieCursor(order, movies[size-1]).Encode()
+	}
+
+	return movies, next, nil
+}
+
+func (s *Storage) GetMovieByFragment(ctx context.Context, fragmentType string, fragment string, fuzzy bool, limit int) ([]models.SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := storage.NewFragmentMatches(fragment, fuzzy)
+	switch fragmentType {
+	case "title":
+		for _, movie := range s.movies {
+			matches.Add(movie, movie.Title)
+		}
+	case "actor":
+		for _, r := range s.rules {
+			movie, ok := s.movies[r.movieId]
+			if !ok {
+				continue
+			}
+			actor, ok := s.actors[r.actorId]
+			if !ok {
+				continue
+			}
+			matches.Add(movie, actor.Name)
+		}
+	default:
+		return nil, fmt.Errorf("%s %q, %w", "storage.memory.GetMovieByFragment", fragmentType, storage.ErrInvalidFilter)
+	}
+
+	return matches.Results(limit), nil
+}
+
+// SearchMovies matches every word of q as a prefix of some word in a
+// movie's title, cast names or description. Title hits weigh most, then
+// cast, then description, roughly like the SQL backends rank them.
+func (s *Storage) SearchMovies(ctx context.Context, q string, limit int) ([]models.SearchResult, error) {
+	terms := storage.SearchTerms(q)
+	results := []models.SearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, movie := range s.movies {
+		names := make([]string, 0)
+		for _, actor := range s.castOf(movie.Id) {
+			names = append(names, actor.Name)
+		}
+
+		score, snippet, ok := storage.ScoreSearch(terms, movie.Title, strings.Join(names, " "), movie.Description)
+		if !ok {
+			continue
+		}
+
+		results = append(results, models.SearchResult{Movie: movie, Snippet: snippet, Score: score})
+	}
+
+	return storage.RankSearchResults(results, limit), nil
+}
+
+//Rules
+
+func (s *Storage) CreateRule(ctx context.Context, movieId int, cast []models.CastEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.newRules(int64(movieId), cast, s.rules)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.memory.CreateRule", err)
+	}
+	s.rules = append(s.rules, rules...)
+
+	return nil
+}
+
+func (s *Storage) ReplaceRules(ctx context.Context, movieId int64, cast []models.CastEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.newRules(movieId, cast, nil)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.memory.ReplaceRules", err)
+	}
+	s.removeRules(func(r rule) bool { return r.movieId == movieId })
+	s.rules = append(s.rules, rules...)
+
+	return nil
+}
+
+func (s *Storage) DeleteRules(ctx context.Context, movieId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.movies[movieId]; !ok {
+		return fmt.Errorf("%s, %w", "storage.memory.DeleteRules", storage.ErrMovieNotFound)
+	}
+	s.removeRules(func(r rule) bool { return r.movieId == movieId })
+
+	return nil
+}
+
+//Users
+
+func (s *Storage) CreateUser(ctx context.Context, username string, passwordHash string, role models.UserRole) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; ok {
+		return 0, fmt.Errorf("%s, %w", "storage.memory.CreateUser", storage.ErrUserExists)
+	}
+
+	s.lastUserId++
+	s.users[username] = models.User{
+		Id:           s.lastUserId,
+		Username:     username,
+		Role:         role,
+		PasswordHash: passwordHash,
+	}
+
+	return s.lastUserId, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, username string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s, %w", "storage.memory.GetUser", storage.ErrUserNotFound)
+	}
+
+	return user, nil
+}
+
+//Refresh tokens
+
+func (s *Storage) CreateRefreshToken(ctx context.Context, tokenId string, userId int64, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, expires := range s.refresh {
+		if expires.Before(now) {
+			delete(s.refresh, id)
+		}
+	}
+	s.refresh[tokenId] = expiresAt
+
+	return nil
+}
+
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.refresh[tokenId]
+	if !ok || expiresAt.Before(time.Now()) {
+		return fmt.Errorf("%s, %w", "storage.memory.UseRefreshToken", storage.ErrRefreshTokenNotFound)
+	}
+	delete(s.refresh, tokenId)
+
+	return nil
+}
+
+//API keys
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key models.APIKey) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.Prefix == key.Prefix {
+			return 0, fmt.Errorf("%s, %w", "storage.memory.CreateAPIKey", storage.ErrAPIKeyExists)
+		}
+	}
+
+	s.lastAPIKeyId++
+	key.Id = s.lastAPIKeyId
+	key.Scopes = slices.Clone(key.Scopes)
+	key.CreatedAt = time.Now().UTC()
+	key.LastUsedAt = nil
+	s.keys[key.Id] = key
+
+	return key.Id, nil
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, prefix string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Prefix == prefix {
+			return copyAPIKey(key), nil
+		}
+	}
+
+	return models.APIKey{}, fmt.Errorf("%s, %w", "storage.memory.GetAPIKey", storage.ErrAPIKeyNotFound)
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, copyAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Id < keys[j].Id })
+
+	return keys, nil
+}
+
+func (s *Storage) DeleteAPIKey(ctx context.Context, keyId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[keyId]; !ok {
+		return fmt.Errorf("%s, %w", "storage.memory.DeleteAPIKey", storage.ErrAPIKeyNotFound)
+	}
+	delete(s.keys, keyId)
+
+	return nil
+}
+
+func (s *Storage) TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[keyId]
+	if !ok {
+		return fmt.Errorf("%s, %w", "storage.memory.TouchAPIKey", storage.ErrAPIKeyNotFound)
+	}
+	key.LastUsedAt = &usedAt
+	s.keys[keyId] = key
+
+	return nil
+}
+
+// copyAPIKey returns key with its own scopes and timestamps, so that callers
+// can't change the stored key through them.
+func copyAPIKey(key models.APIKey) models.APIKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	if key.ExpiresAt != nil {
+		expiresAt := *key.ExpiresAt
+		key.ExpiresAt = &expiresAt
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		key.LastUsedAt = &lastUsedAt
+	}
+
+	return key
+}
+
+// newRules validates links the way the sqlite foreign and primary keys do
+// and returns them without storing. Callers must hold s.mu.
+func (s *Storage) newRules(movieId int64, cast []models.CastEntry, existing []rule) ([]rule, error) {
+	if _, ok := s.movies[movieId]; !ok {
+		return nil, storage.ErrMovieNotFound
+	}
+
+	var rules []rule
+	for _, entry := range cast {
+		if _, ok := s.actors[entry.ActorId]; !ok {
+			return nil, fmt.Errorf("%d, %w", entry.ActorId, storage.ErrActorNotFound)
+		}
+
+		sameLink := func(r rule) bool { return r.movieId == movieId && r.actorId == entry.ActorId }
+		if slices.ContainsFunc(existing, sameLink) || slices.ContainsFunc(rules, sameLink) {
+			return nil, fmt.Errorf("%d, %w", entry.ActorId, storage.ErrRuleExists)
+		}
+
+		role := entry.Role
+		if role.CreditType == "" {
+			role.CreditType = models.CreditSupporting
+		}
+		rules = append(rules, rule{movieId: movieId, actorId: entry.ActorId, role: role})
+	}
+
+	return rules, nil
+}
+
+// removeRules drops every link matching fn. Callers must hold s.mu.
+func (s *Storage) removeRules(fn func(r rule) bool) {
+	rules := s.rules[:0]
+	for _, r := range s.rules {
+		if !fn(r) {
+			rules = append(rules, r)
+		}
+	}
+	s.rules = rules
+}
+
+// castOf returns the actors linked to a movie in billing order. Callers
+// must hold s.mu.
+func (s *Storage) castOf(movieId int64) []models.CastMember {
+	cast := make([]models.CastMember, 0)
+	for _, r := range s.rules {
+		if r.movieId != movieId {
+			continue
+		}
+		if actor, ok := s.actors[r.actorId]; ok {
+			cast = append(cast, models.CastMember{Id: actor.Id, Name: actor.Name, Gender: actor.Gender, Role: r.role})
+		}
+	}
+
+	sort.SliceStable(cast, func(i, j int) bool {
+		if cast[i].Role.BillingOrder != cast[j].Role.BillingOrder {
+			return cast[i].Role.BillingOrder < cast[j].Role.BillingOrder
+		}
+		return cast[i].Id < cast[j].Id
+	})
+
+	return cast
+}
+
+// filmographyOf returns the movies an actor plays in, oldest first. Callers
+// must hold s.mu.
+func (s *Storage) filmographyOf(actorId int64) []models.MovieSummary {
+	movies := []models.MovieSummary{}
+	for _, r := range s.rules {
+		if r.actorId != actorId {
+			continue
+		}
+		if movie, ok := s.movies[r.movieId]; ok {
+			movies = append(movies, models.MovieSummary{
+				Id:     movie.Id,
+				Title:  movie.Title,
+				Date:   movie.Date,
+				Rating: movie.Rating,
+				Role:   r.role,
+			})
+		}
+	}
+
+	sort.Slice(movies, func(i, j int) bool {
+		if !movies[i].Date.Equal(movies[j].Date) {
+			return movies[i].Date.Before(movies[j].Date)
+		}
+		return movies[i].Id < movies[j].Id
+	})
+
+	return movies
+}
+
+// titleTaken reports whether another movie already uses title. Callers must
+// hold s.mu.
+func (s *Storage) titleTaken(title string, exceptId int64) bool {
+	for _, movie := range s.movies {
+		if movie.Id != exceptId && movie.Title == title {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches reports whether movie passes every set field of filter. Callers
+// must hold s.mu.
+func (s *Storage) matches(filter storage.MovieFilter, movie models.Movie) bool {
+	if filter.Title != "" && !containsFold(movie.Title, filter.Title) {
+		return false
+	}
+	if filter.Actor != "" {
+		found := false
+		for _, r := range s.rules {
+			if r.movieId != movie.Id {
+				continue
+			}
+			if actor, ok := s.actors[r.actorId]; ok && containsFold(actor.Name, filter.Actor) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.YearFrom != nil && movie.Date.Year() < *filter.YearFrom {
+		return false
+	}
+	if filter.YearTo != nil && movie.Date.Year() > *filter.YearTo {
+		return false
+	}
+	if filter.RatingMin != nil && movie.Rating < *filter.RatingMin {
+		return false
+	}
+	if filter.RatingMax != nil && movie.Rating > *filter.RatingMax {
+		return false
+	}
+
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// movieLess orders movies the way the SQL backends do: by each key of
+// order in turn, then by ascending id.
+func movieLess(order storage.Order, a, b models.Movie) bool {
+	for _, key := range order {
+		var cmp int
+		switch key.Field {
+		case storage.SortTitle:
+			cmp = strings.Compare(a.Title, b.Title)
+		case storage.SortDate:
+			// Cursors keep only the day, so compare no finer than that.
+			cmp = strings.Compare(a.Date.Format(time.DateOnly), b.Date.Format(time.DateOnly))
+		case storage.SortRating:
+			cmp = a.Rating - b.Rating
+		}
+
+		if cmp != 0 {
+			return (cmp < 0) != key.Desc
+		}
+	}
+
+	return a.Id < b.Id
+}
+
+// cursorMovie turns a cursor back into a movie movieLess can compare with.
+func cursorMovie(order storage.Order, cursor storage.Cursor) (*models.Movie, error) {
+	if len(cursor.Values) != len(order) {
+		return nil, storage.ErrInvalidCursor
+	}
+
+	movie := &models.Movie{Id: cursor.Id}
+	for i, key := range order {
+		var err error
+		switch key.Field {
+		case storage.SortTitle:
+			movie.Title = cursor.Values[i]
+		case storage.SortDate:
+			movie.Date, err = time.Parse(time.DateOnly, cursor.Values[i])
+		case storage.SortRating:
+			movie.Rating, err = strconv.Atoi(cursor.Values[i])
+		}
+		if err != nil {
+			return nil, storage.ErrInvalidCursor
+		}
+	}
+
+	return movie, nil
+}
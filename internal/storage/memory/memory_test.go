@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/storagetest"
+)
+
+func TestGetMoviesSorted(t *testing.T) {
+	storagetest.MoviesSorted(t, New())
+}
+
+func TestGetMovieByFragment(t *testing.T) {
+	storagetest.FragmentSearch(t, New())
+}
+
+func TestDelete(t *testing.T) {
+	storagetest.Delete(t, New())
+}
+
+func TestRefreshTokens(t *testing.T) {
+	storagetest.RefreshTokens(t, New())
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.APIKeys(t, New())
+}
+
+func TestSearchMovies(t *testing.T) {
+	storagetest.Search(t, New())
+}
+
+// TestDatesPagedByDay checks that release dates carrying a time of day,
+// which cursors drop, still page by day and then by id like the SQL
+// backends do.
+func TestDatesPagedByDay(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	for _, date := range []time.Time{
+		time.Date(1979, 5, 25, 20, 0, 0, 0, time.UTC),
+		time.Date(1979, 5, 25, 8, 0, 0, 0, time.UTC),
+		time.Date(1979, 5, 24, 23, 59, 0, 0, time.UTC),
+		time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := s.CreateMovie(ctx, "Alien "+date.Format(time.TimeOnly), "", date, 8); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		sortBy string
+		want   []int64
+	}{
+		{"date", []int64{3, 1, 2, 4}},
+		{"date:desc", []int64{1, 2, 4, 3}},
+	} {
+		var ids []int64
+		page := storage.Page{Limit: 1}
+		for range 5 {
+			movies, next, err := s.GetMoviesSorted(ctx, storage.MovieFilter{}, tt.sortBy, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, movie := range movies {
+				ids = append(ids, movie.Id)
+			}
+			if next == "" {
+				break
+			}
+			page.Cursor = next
+		}
+
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%s: ids = %v, want %v", tt.sortBy, ids, tt.want)
+		}
+	}
+}
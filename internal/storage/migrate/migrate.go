@@ -0,0 +1,359 @@
+// Package migrate applies numbered, embedded SQL migrations and records them
+// in a schema_migrations table.
+//
+// Migrations are files named NNNN_name.up.sql and NNNN_name.down.sql. Each
+// one runs in its own transaction.
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDatabaseAhead = errors.New("database schema is newer than the binary")
+	ErrNoMigrations  = errors.New("no migrations to roll back")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one known migration. Skipped ones were left out with
+// Without; they may still be applied from an earlier run.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Skipped   bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	skipped    []Migration
+}
+
+// New reads migrations from dir inside fsys and makes sure the
+// schema_migrations table exists.
+func New(db *sql.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := load(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.migrate.New.Load", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER NOT NULL PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.migrate.New.Exec", err)
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Without drops the migration with version from m, for an optional
+// feature the database cannot support. It is neither applied nor rolled
+// back. Databases that already have it keep it, and a later binary that
+// supports the feature sees the gap and applies it then.
+func (m *Migrator) Without(version int) *Migrator {
+	migrations := make([]Migration, 0, len(m.migrations))
+	skipped := append([]Migration(nil), m.skipped...)
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			skipped = append(skipped, migration)
+			continue
+		}
+		migrations = append(migrations, migration)
+	}
+
+	return &Migrator{db: m.db, migrations: migrations, skipped: skipped}
+}
+
+// Latest returns the highest version the binary knows about.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the highest version applied to the database. Lower ones
+// may still be missing; Pending tells.
+func (m *Migrator) Version() (int, error) {
+	var version sql.NullInt64
+
+	err := m.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", "storage.migrate.Version.Scan", err)
+	}
+
+	return int(version.Int64), nil
+}
+
+// Check returns ErrDatabaseAhead if the database has migrations applied
+// that this binary does not know about.
+func (m *Migrator) Check() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	return m.check(applied)
+}
+
+func (m *Migrator) check(applied map[int]time.Time) error {
+	for version := range applied {
+		if !m.known(version) {
+			return fmt.Errorf("%s, %w: database has %04d, binary is at %04d",
+				"storage.migrate.Check", ErrDatabaseAhead, version, m.Latest())
+		}
+	}
+
+	return nil
+}
+
+// known reports whether version is one of the binary's migrations, skipped
+// or not.
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	for _, migration := range m.skipped {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pending reports whether there are migrations left to apply, including
+// ones below the highest applied version.
+func (m *Migrator) Pending() (bool, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return false, err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Up applies every pending migration in order.
+func (m *Migrator) Up() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	if err := m.check(applied); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.exec(migration.Up,
+			fmt.Sprintf("INSERT INTO schema_migrations(version) VALUES(%d)", migration.Version))
+		if err != nil {
+			return fmt.Errorf("%s %04d_%s, %w", "storage.migrate.Up", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down rolls back the applied migration with the highest version. Skipped
+// migrations are left in place.
+func (m *Migrator) Down() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	if err := m.check(applied); err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.exec(migration.Down,
+			fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %d", migration.Version))
+		if err != nil {
+			return fmt.Errorf("%s %04d_%s, %w", "storage.migrate.Down", migration.Version, migration.Name, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%s, %w", "storage.migrate.Down", ErrNoMigrations)
+}
+
+// Status lists every known migration, skipped ones included, and whether
+// it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations)+len(m.skipped))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	for _, migration := range m.skipped {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+			Skipped:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.migrate.applied.Query", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("%s, %w", "storage.migrate.applied.Scan", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.migrate.applied.RowsErr", err)
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) exec(statements ...string) (err error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("bad migration name: %s", name)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("bad migration version: %s", name)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: title}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
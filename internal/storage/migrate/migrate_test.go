@@ -0,0 +1,239 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// files holds three migrations, each creating one table.
+var files = fstest.MapFS{
+	"m/0001_one.up.sql":     {Data: []byte("CREATE TABLE one(id INTEGER);")},
+	"m/0001_one.down.sql":   {Data: []byte("DROP TABLE one;")},
+	"m/0002_two.up.sql":     {Data: []byte("CREATE TABLE two(id INTEGER);")},
+	"m/0002_two.down.sql":   {Data: []byte("DROP TABLE two;")},
+	"m/0003_three.up.sql":   {Data: []byte("CREATE TABLE three(id INTEGER);")},
+	"m/0003_three.down.sql": {Data: []byte("DROP TABLE three;")},
+	"m/README":              {Data: []byte("not a migration")},
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+
+	m, err := New(db, fsys, "m")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return m
+}
+
+// tables lists the tables the migrations created.
+func tables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func pending(t *testing.T, m *Migrator) bool {
+	t.Helper()
+
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+
+	return pending
+}
+
+func TestUpDown(t *testing.T) {
+	db := openDB(t)
+	m := newMigrator(t, db, files)
+
+	if m.Latest() != 3 || !pending(t, m) {
+		t.Fatalf("fresh database: latest %d, pending %v", m.Latest(), pending(t, m))
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if pending(t, m) {
+		t.Errorf("pending after Up")
+	}
+	if version, err := m.Version(); err != nil || version != 3 {
+		t.Errorf("Version() = %d, %v, want 3", version, err)
+	}
+	if got := tables(t, db); !slices.Equal(got, []string{"one", "three", "two"}) {
+		t.Errorf("tables = %v", got)
+	}
+	if err := m.Up(); err != nil {
+		t.Errorf("Up again: %v", err)
+	}
+
+	for _, want := range [][]string{{"one", "two"}, {"one"}, {}} {
+		if err := m.Down(); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		if got := tables(t, db); !slices.Equal(got, want) {
+			t.Errorf("tables after Down = %v, want %v", got, want)
+		}
+	}
+	if err := m.Down(); !errors.Is(err, ErrNoMigrations) {
+		t.Errorf("Down on an empty schema: err = %v, want %v", err, ErrNoMigrations)
+	}
+}
+
+func TestWithoutAppliesTheGapLater(t *testing.T) {
+	db := openDB(t)
+	full := newMigrator(t, db, files)
+	without := full.Without(2)
+
+	if err := without.Up(); err != nil {
+		t.Fatalf("Up without 2: %v", err)
+	}
+	if got := tables(t, db); !slices.Equal(got, []string{"one", "three"}) {
+		t.Errorf("tables = %v, want one and three", got)
+	}
+	if pending(t, without) {
+		t.Errorf("pending without 2, which is skipped")
+	}
+
+	// 0002 sits below the highest applied version but is still missing.
+	if !pending(t, full) {
+		t.Errorf("not pending with 2 back and unapplied")
+	}
+	if err := full.Up(); err != nil {
+		t.Fatalf("Up with 2: %v", err)
+	}
+	if got := tables(t, db); !slices.Equal(got, []string{"one", "three", "two"}) {
+		t.Errorf("tables = %v, want all three", got)
+	}
+	if pending(t, full) {
+		t.Errorf("pending after applying 2")
+	}
+
+	statuses, err := without.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, status := range statuses {
+		versions = append(versions, status.Version)
+		if status.Skipped != (status.Version == 2) || !status.Applied {
+			t.Errorf("status %+v", status)
+		}
+	}
+	if !slices.Equal(versions, []int{1, 2, 3}) {
+		t.Errorf("status versions = %v, want 1 2 3", versions)
+	}
+}
+
+func TestDownLeavesSkipped(t *testing.T) {
+	db := openDB(t)
+	full := newMigrator(t, db, files)
+	if err := full.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A binary that can't handle 0002 must not try to roll it back.
+	without := full.Without(2)
+	for range 2 {
+		if err := without.Down(); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+	}
+	if got := tables(t, db); !slices.Equal(got, []string{"two"}) {
+		t.Errorf("tables = %v, want only the skipped two", got)
+	}
+	if err := without.Down(); !errors.Is(err, ErrNoMigrations) {
+		t.Errorf("Down with only a skipped migration left: err = %v, want %v", err, ErrNoMigrations)
+	}
+
+	if err := full.Down(); err != nil {
+		t.Errorf("Down with 2 back: %v", err)
+	}
+	if got := tables(t, db); len(got) != 0 {
+		t.Errorf("tables = %v, want none", got)
+	}
+}
+
+func TestDatabaseAhead(t *testing.T) {
+	db := openDB(t)
+	if err := newMigrator(t, db, files).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	older := fstest.MapFS{}
+	for name, file := range files {
+		if filepath.Base(name)[:4] != "0003" {
+			older[name] = file
+		}
+	}
+	m := newMigrator(t, db, older)
+
+	if err := m.Check(); !errors.Is(err, ErrDatabaseAhead) {
+		t.Errorf("Check: err = %v, want %v", err, ErrDatabaseAhead)
+	}
+	if err := m.Up(); !errors.Is(err, ErrDatabaseAhead) {
+		t.Errorf("Up: err = %v, want %v", err, ErrDatabaseAhead)
+	}
+	if err := m.Down(); !errors.Is(err, ErrDatabaseAhead) {
+		t.Errorf("Down: err = %v, want %v", err, ErrDatabaseAhead)
+	}
+
+	// A skipped migration is still known.
+	if err := newMigrator(t, db, files).Without(3).Check(); err != nil {
+		t.Errorf("Check with 3 skipped: %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"no down", fstest.MapFS{"m/0001_one.up.sql": {Data: []byte("SELECT 1;")}}},
+		{"no name", fstest.MapFS{"m/0001.up.sql": {}, "m/0001.down.sql": {}}},
+		{"bad version", fstest.MapFS{"m/first_one.up.sql": {}, "m/first_one.down.sql": {}}},
+		{"zero version", fstest.MapFS{"m/0000_one.up.sql": {}, "m/0000_one.down.sql": {}}},
+		{"no directory", fstest.MapFS{}},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(openDB(t), tt.files, "m"); err == nil {
+			t.Errorf("%s: New succeeded", tt.name)
+		}
+	}
+}
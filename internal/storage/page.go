@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// sortActors is the only ordering actor listings support.
+const sortActors = "id"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects one page of a listing. An empty Cursor starts from the top.
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// Size returns the page size clamped to [1, MaxLimit].
+func (p Page) Size() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultLimit
+	case p.Limit > MaxLimit:
+		return MaxLimit
+	}
+
+	return p.Limit
+}
+
+// Cursor points at the last row of a page: the values of its sort keys and
+// the id used as tie-breaker. It travels to clients base64-encoded.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v,omitempty"`
+	Id     int64    `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses an opaque cursor and checks it was issued for the
+// same ordering.
+func DecodeCursor(cursor string, sortBy string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sortBy {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// MovieCursor builds the cursor that continues a listing in order after
+// movie.
+func MovieCursor(order Order, movie models.Movie) Cursor {
+	c := Cursor{Sort: order.String(), Id: movie.Id}
+
+	for _, key := range order {
+		switch key.Field {
+		case SortTitle:
+			c.Values = append(c.Values, movie.Title)
+		case SortDate:
+			c.Values = append(c.Values, movie.Date.Format(time.DateOnly))
+		case SortRating:
+			c.Values = append(c.Values, strconv.Itoa(movie.Rating))
+		}
+	}
+
+	return c
+}
+
+// ActorCursor builds the cursor that continues a listing after actor.
+func ActorCursor(actor models.Actor) Cursor {
+	return Cursor{Sort: sortActors, Id: actor.Id}
+}
+
+// DecodeActorCursor parses a cursor issued by ActorCursor.
+func DecodeActorCursor(cursor string) (Cursor, error) {
+	return DecodeCursor(cursor, sortActors)
+}
@@ -0,0 +1,18 @@
+package storage
+
+import "time"
+
+// MoviePatch is a partial update of a movie. Nil fields are left unchanged.
+type MoviePatch struct {
+	Title       *string
+	Description *string
+	Date        *time.Time
+	Rating      *int8
+}
+
+// ActorPatch is a partial update of an actor. Nil fields are left unchanged.
+type ActorPatch struct {
+	Name   *string
+	Gender *string
+	Birth  *time.Time
+}
@@ -0,0 +1,245 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/migrate"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/storagetest"
+)
+
+// The integration tests run against the server in POSTGRES_TEST_DSN, or
+// against an embedded one downloaded on first use:
+//
+//	go test -tags integration ./internal/storage/postgres/
+//
+// Every test gets a database of its own.
+
+var (
+	adminDSN  string
+	databases atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if testing.Short() {
+		os.Exit(m.Run())
+	}
+
+	adminDSN = os.Getenv("POSTGRES_TEST_DSN")
+	if adminDSN != "" {
+		os.Exit(m.Run())
+	}
+
+	dir, err := os.MkdirTemp("", "cinema-postgres")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(dir).
+		Logger(io.Discard)
+	server := embeddedpostgres.NewDatabase(config)
+	if err := server.Start(); err != nil {
+		os.RemoveAll(dir)
+		log.Fatalf("start embedded postgres: %v", err)
+	}
+	adminDSN = config.GetConnectionURL() + "?sslmode=disable"
+
+	code := m.Run()
+
+	if err := server.Stop(); err != nil {
+		log.Printf("stop embedded postgres: %v", err)
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// newTestStorage creates an empty database and opens it with New, so every
+// migration has run.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("postgres integration test")
+	}
+
+	admin, err := sql.Open("pgx", adminDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	name := fmt.Sprintf("cinema_test_%d_%d", os.Getpid(), databases.Add(1))
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		admin, err := sql.Open("pgx", adminDSN)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)")
+	})
+
+	dsn, err := url.Parse(adminDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn.Path = "/" + name
+
+	s, err := New(dsn.String())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestMigrations(t *testing.T) {
+	m := newTestStorage(t).Migrator()
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != m.Latest() {
+		t.Fatalf("version = %d, want %d", version, m.Latest())
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %04d_%s not applied", status.Version, status.Name)
+		}
+	}
+
+	for i := 0; i < len(statuses); i++ {
+		if err := m.Down(); err != nil {
+			t.Fatalf("Down from %d: %v", statuses[len(statuses)-1-i].Version, err)
+		}
+	}
+	if err := m.Down(); !errors.Is(err, migrate.ErrNoMigrations) {
+		t.Fatalf("Down on an empty schema: err = %v, want %v", err, migrate.ErrNoMigrations)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up again: %v", err)
+	}
+	if pending, err := m.Pending(); err != nil || pending {
+		t.Fatalf("Pending() = %v, %v, want false", pending, err)
+	}
+}
+
+func TestGetMoviesSorted(t *testing.T) {
+	storagetest.MoviesSorted(t, newTestStorage(t))
+}
+
+func TestGetMovieByFragment(t *testing.T) {
+	storagetest.FragmentSearch(t, newTestStorage(t))
+}
+
+func TestDelete(t *testing.T) {
+	storagetest.Delete(t, newTestStorage(t))
+}
+
+func TestSearchMovies(t *testing.T) {
+	storagetest.Search(t, newTestStorage(t))
+}
+
+func TestRefreshTokens(t *testing.T) {
+	storagetest.RefreshTokens(t, newTestStorage(t))
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.APIKeys(t, newTestStorage(t))
+}
+
+func TestUniqueViolations(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	date := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+
+	movieId, err := s.CreateMovie(ctx, "Alien", "", date, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherId, err := s.CreateMovie(ctx, "Aliens", "", date, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actorId, err := s.CreateActor(ctx, "Sigourney Weaver", "female", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateRule(ctx, int(movieId), []models.CastEntry{{ActorId: actorId}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateUser(ctx, "ripley", "hash", models.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateAPIKey(ctx, models.APIKey{Name: "ci", Prefix: "0123456789ab", Hash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.CreateMovie(ctx, "Alien", "", date, 8)
+	wantErr(t, "movie", err, storage.ErrFilmExists)
+
+	title := "Alien"
+	_, err = s.UpdateMovie(ctx, otherId, storage.MoviePatch{Title: &title})
+	wantErr(t, "movie title update", err, storage.ErrFilmExists)
+
+	err = s.CreateRule(ctx, int(movieId), []models.CastEntry{{ActorId: actorId}})
+	wantErr(t, "rule", err, storage.ErrRuleExists)
+
+	_, err = s.CreateUser(ctx, "ripley", "hash", models.RoleUser)
+	wantErr(t, "user", err, storage.ErrUserExists)
+
+	_, err = s.CreateAPIKey(ctx, models.APIKey{Name: "ci", Prefix: "0123456789ab", Hash: "b"})
+	wantErr(t, "api key", err, storage.ErrAPIKeyExists)
+}
+
+func wantErr(t *testing.T, name string, err error, want error) {
+	t.Helper()
+
+	if !errors.Is(err, want) {
+		t.Errorf("%s: err = %v, want %v", name, err, want)
+	}
+}
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS movies(
+	id BIGSERIAL PRIMARY KEY,
+	title TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL,
+	date DATE NOT NULL,
+	rating INTEGER NOT NULL);
+
+CREATE TABLE IF NOT EXISTS actors(
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	gender TEXT,
+	birthDate DATE);
+
+CREATE TABLE IF NOT EXISTS rules(
+	movie_id BIGINT NOT NULL,
+	actor_id BIGINT);
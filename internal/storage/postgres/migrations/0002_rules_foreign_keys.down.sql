@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS rules_actor_id;
+
+ALTER TABLE rules
+	DROP CONSTRAINT rules_actor_id_fkey,
+	DROP CONSTRAINT rules_movie_id_fkey,
+	DROP CONSTRAINT rules_pkey,
+	ALTER COLUMN actor_id DROP NOT NULL;
@@ -0,0 +1,17 @@
+-- Orphaned and duplicate links are dropped before the constraints go on.
+DELETE FROM rules r
+WHERE r.actor_id IS NULL
+	OR NOT EXISTS (SELECT 1 FROM movies m WHERE m.id = r.movie_id)
+	OR NOT EXISTS (SELECT 1 FROM actors a WHERE a.id = r.actor_id);
+
+DELETE FROM rules a
+USING rules b
+WHERE a.ctid < b.ctid AND a.movie_id = b.movie_id AND a.actor_id = b.actor_id;
+
+ALTER TABLE rules
+	ALTER COLUMN actor_id SET NOT NULL,
+	ADD CONSTRAINT rules_pkey PRIMARY KEY (movie_id, actor_id),
+	ADD CONSTRAINT rules_movie_id_fkey FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE,
+	ADD CONSTRAINT rules_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES actors(id) ON DELETE CASCADE;
+
+CREATE INDEX rules_actor_id ON rules(actor_id);
@@ -0,0 +1,1103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/migrate"
+)
+
+// uniqueViolation is the SQLSTATE Postgres reports for a broken UNIQUE
+// constraint.
+const uniqueViolation = "23505"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type Storage struct {
+	db       *sql.DB
+	migrator *migrate.Migrator
+}
+
+var _ storage.Repository = (*Storage)(nil)
+
+// New connects to the database and applies any pending migrations. It
+// refuses to start if the database has migrations this binary does not know
+// about.
+func New(dsn string) (*Storage, error) {
+	s, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.migrator.Up(); err != nil {
+		s.db.Close()
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.New.Migrate", err)
+	}
+
+	return s, nil
+}
+
+// Open connects to the database without touching its schema.
+func Open(dsn string) (*Storage, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.Open.Open", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.Open.Ping", err)
+	}
+
+	migrator, err := migrate.New(db, migrations, "migrations")
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.Open.Migrator", err)
+	}
+
+	return &Storage{db: db, migrator: migrator}, nil
+}
+
+func (s *Storage) Migrator() *migrate.Migrator {
+	return s.migrator
+}
+
+// DB exposes the connection pool for health checks and pool statistics.
+func (s *Storage) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// Actor
+func (s *Storage) CreateActor(ctx context.Context, name string, gender string, birth time.Time) (int64, error) {
+	var id int64
+
+	err := s.db.QueryRowContext(ctx, "INSERT INTO actors(name, gender, birthDate) VALUES($1, $2, $3) RETURNING id",
+		name, gender, birth).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateActor.Exec", storage.ErrActorExists)
+		}
+
+		return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateActor.Exec", err)
+	}
+
+	return id, nil
+}
+
+// UpdateActor applies patch and returns the updated actor.
+func (s *Storage) UpdateActor(ctx context.Context, actorId int64, patch storage.ActorPatch) (models.Actor, error) {
+	var u update
+	if patch.Name != nil {
+		u.set("name", *patch.Name)
+	}
+	if patch.Gender != nil {
+		u.set("gender", *patch.Gender)
+	}
+	if patch.Birth != nil {
+		u.set("birthDate", *patch.Birth)
+	}
+
+	if len(u.sets) > 0 {
+		query, args := u.query("actors", actorId)
+		result, err := s.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateActor.Exec", storage.ErrActorExists)
+			}
+
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateActor.Exec", err)
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateActor.RowsAffected", err)
+		}
+		if n == 0 {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateActor", storage.ErrActorNotFound)
+		}
+	}
+
+	return s.GetActor(ctx, actorId)
+}
+
+func (s *Storage) DeleteActor(ctx context.Context, actorId int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM actors WHERE id = $1", actorId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteActor.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteActor.RowsAffected", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteActor", storage.ErrActorNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetActor(ctx context.Context, actorId int64) (models.Actor, error) {
+	var actor models.Actor
+	var gender sql.NullString
+	var birth sql.NullTime
+
+	err := s.db.QueryRowContext(ctx, "SELECT id, name, gender, birthDate FROM actors WHERE id = $1", actorId).
+		Scan(&actor.Id, &actor.Name, &gender, &birth)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.GetActor.Scan", storage.ErrActorNotFound)
+		}
+
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.GetActor.Scan", err)
+	}
+
+	actor.Gender = gender.String
+	actor.Birth = birth.Time
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT m.id, m.title, m.date, m.rating, r.character_name, r.billing_order, r.credit_type
+	FROM movies m
+	JOIN rules r ON m.id = r.movie_id
+	WHERE r.actor_id = $1
+	ORDER BY m.date, m.id
+`, actorId)
+	if err != nil {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.GetActor.Query", err)
+	}
+	defer rows.Close()
+
+	actor.Movies = []models.MovieSummary{}
+	for rows.Next() {
+		var movie models.MovieSummary
+
+		if err := rows.Scan(&movie.Id, &movie.Title, &movie.Date, &movie.Rating,
+			&movie.Role.Character, &movie.Role.BillingOrder, &movie.Role.CreditType); err != nil {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.GetActor.RowsScan", err)
+		}
+
+		actor.Movies = append(actor.Movies, movie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.postgres.GetActor.RowsError", err)
+	}
+
+	return actor, nil
+}
+
+// GetActors returns one page of actors ordered by id with their
+// filmography, plus the cursor of the next page ("" on the last one).
+// Actors without movies come back with an empty Movies slice.
+func (s *Storage) GetActors(ctx context.Context, page storage.Page) ([]models.Actor, string, error) {
+	var afterId int64
+	if page.Cursor != "" {
+		cursor, err := storage.DecodeActorCursor(page.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetActors.Cursor", err)
+		}
+		afterId = cursor.Id
+	}
+
+	size := page.Size()
+
+	query := `
+	SELECT a.id, a.name, a.gender, a.birthDate,
+		m.id, m.title, m.date, m.rating, r.character_name, r.billing_order, r.credit_type
+	FROM (
+		SELECT * FROM actors WHERE id > $1 ORDER BY id LIMIT $2
+	) a
+	LEFT JOIN rules r ON a.id = r.actor_id
+	LEFT JOIN movies m ON r.movie_id = m.id
+	ORDER BY a.id, m.date, m.id
+`
+
+	rows, err := s.db.QueryContext(ctx, query, afterId, size+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetActors.Query", err)
+	}
+	defer rows.Close()
+
+	actors := []models.Actor{}
+	for rows.Next() {
+		var actorId int64
+		var actorName string
+		var actorGender sql.NullString
+		var actorBirth sql.NullTime
+		var movieId sql.NullInt64
+		var movieTitle sql.NullString
+		var movieDate sql.NullTime
+		var movieRating sql.NullInt64
+		var character sql.NullString
+		var billingOrder sql.NullInt64
+		var creditType sql.NullString
+
+		err := rows.Scan(&actorId, &actorName, &actorGender, &actorBirth,
+			&movieId, &movieTitle, &movieDate, &movieRating, &character, &billingOrder, &creditType)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetActors.Scan", err)
+		}
+
+		if len(actors) == 0 || actors[len(actors)-1].Id != actorId {
+			actors = append(actors, models.Actor{
+				Id:     actorId,
+				Name:   actorName,
+				Gender: actorGender.String,
+				Birth:  actorBirth.Time,
+				Movies: []models.MovieSummary{},
+			})
+		}
+
+		if !movieId.Valid {
+			continue
+		}
+
+		actor := &actors[len(actors)-1]
+		actor.Movies = append(actor.Movies, models.MovieSummary{
+			Id:     movieId.Int64,
+			Title:  movieTitle.String,
+			Date:   movieDate.Time,
+			Rating: int(movieRating.Int64),
+			Role: models.Role{
+				Character:    character.String,
+				BillingOrder: int(billingOrder.Int64),
+				CreditType:   models.CreditType(creditType.String),
+			},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetActors.rowsErr", err)
+	}
+
+	var next string
+	if len(actors) > size {
+		actors = actors[:size]
+		next = storage.ActorCursor(actors[size-1]).Encode()
+	}
+
+	return actors, next, nil
+}
+
+//Movie
+
+func (s *Storage) CreateMovie(ctx context.Context, title string, description string, date time.Time, rating int8) (int64, error) {
+	var id int64
+
+	err := s.db.QueryRowContext(ctx, "INSERT INTO movies(title, description, date, rating) VALUES($1, $2, $3, $4) RETURNING id",
+		title, description, date, rating).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateMovie.Exec", storage.ErrFilmExists)
+		}
+
+		return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateMovie.Exec", err)
+	}
+
+	return id, nil
+}
+
+// UpdateMovie applies patch and returns the updated movie.
+func (s *Storage) UpdateMovie(ctx context.Context, filmId int64, patch storage.MoviePatch) (models.Movie, error) {
+	var u update
+	if patch.Title != nil {
+		u.set("title", *patch.Title)
+	}
+	if patch.Description != nil {
+		u.set("description", *patch.Description)
+	}
+	if patch.Date != nil {
+		u.set("date", *patch.Date)
+	}
+	if patch.Rating != nil {
+		u.set("rating", *patch.Rating)
+	}
+
+	if len(u.sets) > 0 {
+		query, args := u.query("movies", filmId)
+		result, err := s.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateMovie.Exec", storage.ErrFilmExists)
+			}
+
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateMovie.Exec", err)
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateMovie.RowsAffected", err)
+		}
+		if n == 0 {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.UpdateMovie", storage.ErrMovieNotFound)
+		}
+	}
+
+	return s.GetMovie(ctx, filmId)
+}
+
+func (s *Storage) DeliteMovie(ctx context.Context, filmId int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM movies WHERE id = $1", filmId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeliteMovie.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeliteMovie.RowsAffected", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeliteMovie", storage.ErrMovieNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetMovie(ctx context.Context, filmId int64) (models.Movie, error) {
+	var movie models.Movie
+
+	err := s.db.QueryRowContext(ctx, "SELECT id, title, description, date, rating FROM movies WHERE id = $1", filmId).
+		Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Date, &movie.Rating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.GetMovie.Scan", storage.ErrMovieNotFound)
+		}
+
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.GetMovie.Scan", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT a.id, a.name, a.gender, r.character_name, r.billing_order, r.credit_type
+	FROM actors a
+	JOIN rules r ON a.id = r.actor_id
+	WHERE r.movie_id = $1
+	ORDER BY r.billing_order, a.id
+`, filmId)
+	if err != nil {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.GetMovie.Query", err)
+	}
+	defer rows.Close()
+
+	movie.Actors = make([]models.CastMember, 0)
+	for rows.Next() {
+		var actor models.CastMember
+		var gender sql.NullString
+
+		if err := rows.Scan(&actor.Id, &actor.Name, &gender,
+			&actor.Role.Character, &actor.Role.BillingOrder, &actor.Role.CreditType); err != nil {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.GetMovie.RowsScan", err)
+		}
+		actor.Gender = gender.String
+
+		movie.Actors = append(movie.Actors, actor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.postgres.GetMovie.RowsError", err)
+	}
+
+	return movie, nil
+}
+
+// movieSortColumns maps sort fields to the column a page is ordered by.
+var movieSortColumns = map[string]string{
+	storage.SortTitle:  "m.title",
+	storage.SortDate:   "m.date",
+	storage.SortRating: "m.rating",
+}
+
+// GetMoviesSorted returns one page of movies matching filter in the order
+// described by sortBy (see storage.ParseMovieOrder) with id as the final
+// tie-breaker, plus the cursor of the next page ("" on the last one).
+func (s *Storage) GetMoviesSorted(ctx context.Context, filter storage.MovieFilter, sortBy string, page storage.Page) ([]models.Movie, string, error) {
+	order, err := storage.ParseMovieOrder(sortBy)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetMovies.Order", err)
+	}
+
+	conditions, args := moviesWhere(filter, nil)
+	if page.Cursor != "" {
+		cursor, err := storage.DecodeCursor(page.Cursor, order.String())
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetMovies.Cursor", err)
+		}
+
+		var after string
+		after, args, err = moviesAfter(order, cursor, args)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetMovies.Cursor", err)
+		}
+		conditions = append(conditions, after)
+	}
+
+	var where string
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	size := page.Size()
+	args = append(args, size+1)
+
+	orderBy := moviesOrderBy(order)
+	query := `
+            SELECT m.id, m.title, m.description, m.date, m.rating, a.id, a.name, a.gender,
+                r.character_name, r.billing_order, r.credit_type
+            FROM (
+                SELECT * FROM movies m
+                ` + where + `
+                ORDER BY ` + orderBy + `
+                LIMIT $` + strconv.Itoa(len(args)) + `
+            ) m
+            LEFT JOIN rules r ON m.id = r.movie_id
+            LEFT JOIN actors a ON r.actor_id = a.id
+            ORDER BY ` + orderBy + `, r.billing_order, a.id
+        `
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetMovies.Query", err)
+	}
+	defer rows.Close()
+
+	var movies = make([]models.Movie, 0, size+1)
+	for rows.Next() {
+		var movieID int64
+		var movieTitle string
+		var movieDescription string
+		var movieDate time.Time
+		var movieRating int
+		var actorID sql.NullInt64
+		var actorName sql.NullString
+		var actorGender sql.NullString
+		var character sql.NullString
+		var billingOrder sql.NullInt64
+		var creditType sql.NullString
+
+		err := rows.Scan(&movieID, &movieTitle, &movieDescription,
+			&movieDate, &movieRating, &actorID, &actorName, &actorGender,
+			&character, &billingOrder, &creditType)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetMovies.Scan", err)
+		}
+
+		if len(movies) == 0 || movies[len(movies)-1].Id != movieID {
+			movies = append(movies, models.Movie{
+				Id:          movieID,
+				Title:       movieTitle,
+				Description: movieDescription,
+				Date:        movieDate,
+				Rating:      movieRating,
+				Actors:      make([]models.CastMember, 0),
+			})
+		}
+
+		if actorID.Valid && actorName.Valid {
+			movie := &movies[len(movies)-1]
+			movie.Actors = append(movie.Actors, models.CastMember{
+				Id:     actorID.Int64,
+				Name:   actorName.String,
+				Gender: actorGender.String,
+				Role: models.Role{
+					Character:    character.String,
+					BillingOrder: int(billingOrder.Int64),
+					CreditType:   models.CreditType(creditType.String),
+				},
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.postgres.GetMovies.RowsErr", err)
+	}
+
+	var next string
+	if len(movies) > size {
+		movies = movies[:size]
+		next = storage.MovieCursor(order, movies[size-1]).Encode()
+	}
+
+	return movies, next, nil
+}
+
+// GetMovieByFragment finds movies whose title or cast member name contains
+// fragment. With fuzzy set, typos and Cyrillic/Latin transliterations are
+// tolerated too, except in the first letter of a word. Those can't be
+// expressed in SQL, so rows are narrowed down to the ones with a word
+// starting with each term's initials, and every one of them is scored by
+// storage.MatchScore before the best limit are kept.
+func (s *Storage) GetMovieByFragment(ctx context.Context, fragmentType string, fragment string, fuzzy bool, limit int) ([]models.SearchResult, error) {
+	var query, column string
+	switch fragmentType {
+	case "title":
+		query = `
+        SELECT m.id, m.title, m.description, m.date, m.rating, m.title
+        FROM movies m
+    `
+		column = "m.title"
+	case "actor":
+		query = `
+        SELECT m.id, m.title, m.description, m.date, m.rating, a.name
+        FROM movies m
+        JOIN rules r ON m.id = r.movie_id
+        JOIN actors a ON a.id = r.actor_id
+    `
+		column = "a.name"
+	default:
+		return nil, fmt.Errorf("%s %q, %w", "storage.postgres.GetMovieByFragment", fragmentType, storage.ErrInvalidFilter)
+	}
+
+	var args []interface{}
+	if fuzzy {
+		initials := storage.FuzzyInitials(fragment)
+		if len(initials) == 0 {
+			return []models.SearchResult{}, nil
+		}
+
+		// Every term needs a word of column starting with one of its
+		// initials: at the start, or after a character that is not a
+		// letter or digit.
+		var conditions []string
+		for _, letters := range initials {
+			args = append(args, "(^|[^[:alnum:]])["+letters+"]")
+			conditions = append(conditions, column+" ~ $"+strconv.Itoa(len(args)))
+		}
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	} else {
+		query += " WHERE " + column + " ILIKE $1"
+		args = append(args, "%"+fragment+"%")
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.GetMovieByFragment.Query", err)
+	}
+	defer rows.Close()
+
+	matches := storage.NewFragmentMatches(fragment, fuzzy)
+	for rows.Next() {
+		var movie models.Movie
+		var text string
+
+		err := rows.Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Date, &movie.Rating, &text)
+		if err != nil {
+			return nil, fmt.Errorf("%s, %w", "storage.postgres.GetMovieByFragment.RowsScan", err)
+		}
+
+		matches.Add(movie, text)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.GetMovieByFragment.RowsError", err)
+	}
+
+	return matches.Results(limit), nil
+}
+
+// SearchMovies runs a full-text query over titles, descriptions and cast
+// names. Every word of q must match, as a prefix. Results are ranked by
+// ts_rank with title hits weighted highest, then cast, then description.
+func (s *Storage) SearchMovies(ctx context.Context, q string, limit int) ([]models.SearchResult, error) {
+	terms := storage.SearchTerms(q)
+	if len(terms) == 0 {
+		return []models.SearchResult{}, nil
+	}
+
+	match := make([]string, 0, len(terms))
+	for _, term := range terms {
+		match = append(match, term+":*")
+	}
+
+	query := `
+        WITH docs AS (
+            SELECT m.id, m.title, m.description, m.date, m.rating,
+                coalesce(string_agg(a.name, ' '), '') AS actors
+            FROM movies m
+            LEFT JOIN rules r ON r.movie_id = m.id
+            LEFT JOIN actors a ON a.id = r.actor_id
+            GROUP BY m.id
+        ), ranked AS (
+            SELECT d.*,
+                setweight(to_tsvector('simple', d.title), 'A') ||
+                setweight(to_tsvector('simple', d.actors), 'B') ||
+                setweight(to_tsvector('simple', d.description), 'C') AS doc
+            FROM docs d
+        )
+        SELECT id, title, description, date, rating,
+            ts_headline('simple', title || ' ' || actors || ' ' || description, q, $3),
+            ts_rank(doc, q) AS rank
+        FROM ranked, to_tsquery('simple', $1) q
+        WHERE doc @@ q
+        ORDER BY rank DESC, id
+        LIMIT $2
+    `
+	headline := "StartSel=" + storage.MatchStart + ", StopSel=" + storage.MatchEnd + ", MaxWords=16, MinWords=4"
+	rows, err := s.db.QueryContext(ctx, query, strings.Join(match, " & "), limit, headline)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.SearchMovies.Query", err)
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var result models.SearchResult
+
+		err := rows.Scan(&result.Movie.Id, &result.Movie.Title, &result.Movie.Description,
+			&result.Movie.Date, &result.Movie.Rating, &result.Snippet, &result.Score)
+		if err != nil {
+			return nil, fmt.Errorf("%s, %w", "storage.postgres.SearchMovies.RowsScan", err)
+		}
+		result.Snippet = storage.MarkSnippet(result.Snippet)
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.SearchMovies.RowsError", err)
+	}
+
+	return results, nil
+}
+
+//Rules
+
+func (s *Storage) CreateRule(ctx context.Context, movieId int, cast []models.CastEntry) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.CreateRule.txBegin", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = insertRules(ctx, tx, int64(movieId), cast); err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.CreateRule", err)
+	}
+
+	return nil
+}
+
+// ReplaceRules swaps the whole cast of a movie in one transaction.
+func (s *Storage) ReplaceRules(ctx context.Context, movieId int64, cast []models.CastEntry) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.ReplaceRules.txBegin", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM rules WHERE movie_id = $1", movieId); err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.ReplaceRules.Delete", err)
+	}
+
+	if err = insertRules(ctx, tx, movieId, cast); err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.ReplaceRules", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteRules(ctx context.Context, movieId int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM rules WHERE movie_id = $1", movieId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteRules.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteRules.RowsAffected", err)
+	}
+
+	// No links deleted: an empty cast, or no such movie.
+	if n == 0 {
+		var exists int
+		err = s.db.QueryRowContext(ctx, "SELECT 1 FROM movies WHERE id = $1", movieId).Scan(&exists)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s, %w", "storage.postgres.DeleteRules.Movie", storage.ErrMovieNotFound)
+			}
+
+			return fmt.Errorf("%s, %w", "storage.postgres.DeleteRules.Movie", err)
+		}
+	}
+
+	return nil
+}
+
+// insertRules links the cast entries to movieId inside tx, checking both sides first
+// so callers get a typed not-found error instead of a foreign key violation.
+func insertRules(ctx context.Context, tx *sql.Tx, movieId int64, cast []models.CastEntry) error {
+	var exists int
+
+	err := tx.QueryRowContext(ctx, "SELECT 1 FROM movies WHERE id = $1", movieId).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", "insertRules.Movie", storage.ErrMovieNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", "insertRules.Movie", err)
+	}
+
+	for _, entry := range cast {
+		actorId := entry.ActorId
+		creditType := entry.CreditType
+		if creditType == "" {
+			creditType = models.CreditSupporting
+		}
+
+		err := tx.QueryRowContext(ctx, "SELECT 1 FROM actors WHERE id = $1", actorId).Scan(&exists)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s %d, %w", "insertRules.Actor", actorId, storage.ErrActorNotFound)
+			}
+
+			return fmt.Errorf("%s, %w", "insertRules.Actor", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO rules (movie_id, actor_id, character_name, billing_order, credit_type)
+		VALUES($1, $2, $3, $4, $5)`,
+			movieId, actorId, entry.Character, entry.BillingOrder, creditType)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%s %d, %w", "insertRules.Exec", actorId, storage.ErrRuleExists)
+			}
+
+			return fmt.Errorf("%s, %w", "insertRules.Exec", err)
+		}
+	}
+
+	return nil
+}
+
+//Users
+
+func (s *Storage) CreateUser(ctx context.Context, username string, passwordHash string, role models.UserRole) (int64, error) {
+	var id int64
+
+	err := s.db.QueryRowContext(ctx, "INSERT INTO users(username, password_hash, role) VALUES($1, $2, $3) RETURNING id",
+		username, passwordHash, role).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateUser.Exec", storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateUser.Exec", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+
+	err := s.db.QueryRowContext(ctx, "SELECT id, username, password_hash, role FROM users WHERE username = $1", username).
+		Scan(&user.Id, &user.Username, &user.PasswordHash, &user.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s, %w", "storage.postgres.GetUser.Scan", storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s, %w", "storage.postgres.GetUser.Scan", err)
+	}
+
+	return user, nil
+}
+
+//Refresh tokens
+
+func (s *Storage) CreateRefreshToken(ctx context.Context, tokenId string, userId int64, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at < now()")
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.CreateRefreshToken.Purge", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, "INSERT INTO refresh_tokens(id, user_id, expires_at) VALUES($1, $2, $3)",
+		tokenId, userId, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.CreateRefreshToken.Exec", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenId string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE id = $1 AND expires_at >= now()", tokenId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.UseRefreshToken.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.UseRefreshToken.RowsAffected", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.postgres.UseRefreshToken", storage.ErrRefreshTokenNotFound)
+	}
+
+	return nil
+}
+
+//API keys
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key models.APIKey) (int64, error) {
+	var id int64
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO api_keys(name, prefix, key_hash, scopes, expires_at)
+		VALUES($1, $2, $3, $4, $5) RETURNING id`,
+		key.Name, key.Prefix, key.Hash, storage.JoinScopes(key.Scopes), key.ExpiresAt).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateAPIKey.Exec", storage.ErrAPIKeyExists)
+		}
+
+		return 0, fmt.Errorf("%s, %w", "storage.postgres.CreateAPIKey.Exec", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, prefix string) (models.APIKey, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+		FROM api_keys WHERE prefix = $1`, prefix)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("%s, %w", "storage.postgres.GetAPIKey.Scan", storage.ErrAPIKeyNotFound)
+		}
+
+		return models.APIKey{}, fmt.Errorf("%s, %w", "storage.postgres.GetAPIKey.Scan", err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+		FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.ListAPIKeys.Query", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s, %w", "storage.postgres.ListAPIKeys.Scan", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.postgres.ListAPIKeys.Rows", err)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) DeleteAPIKey(ctx context.Context, keyId int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1", keyId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteAPIKey.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteAPIKey.RowsAffected", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.postgres.DeleteAPIKey", storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, keyId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.TouchAPIKey.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.postgres.TouchAPIKey.RowsAffected", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.postgres.TouchAPIKey", storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey reads one api_keys row selected in the column order used above.
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&key.Id, &key.Name, &key.Prefix, &key.Hash, &scopes, &expiresAt, &lastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key.Scopes = storage.SplitScopes(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return key, nil
+}
+
+// moviesOrderBy renders order as an ORDER BY list ending in m.id.
+func moviesOrderBy(order storage.Order) string {
+	parts := make([]string, 0, len(order)+1)
+	for _, key := range order {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, movieSortColumns[key.Field]+" "+direction)
+	}
+
+	return strings.Join(append(parts, "m.id"), ", ")
+}
+
+// moviesWhere renders the filter as conditions on the movies table m,
+// numbering placeholders after the ones already in args.
+func moviesWhere(filter storage.MovieFilter, args []interface{}) ([]string, []interface{}) {
+	var conditions []string
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Title != "" {
+		conditions = append(conditions, "m.title ILIKE "+arg("%"+filter.Title+"%"))
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, `EXISTS (
+                    SELECT 1 FROM rules r JOIN actors a ON a.id = r.actor_id
+                    WHERE r.movie_id = m.id AND a.name ILIKE `+arg("%"+filter.Actor+"%")+`)`)
+	}
+	if filter.YearFrom != nil {
+		conditions = append(conditions, "EXTRACT(YEAR FROM m.date) >= "+arg(*filter.YearFrom))
+	}
+	if filter.YearTo != nil {
+		conditions = append(conditions, "EXTRACT(YEAR FROM m.date) <= "+arg(*filter.YearTo))
+	}
+	if filter.RatingMin != nil {
+		conditions = append(conditions, "m.rating >= "+arg(*filter.RatingMin))
+	}
+	if filter.RatingMax != nil {
+		conditions = append(conditions, "m.rating <= "+arg(*filter.RatingMax))
+	}
+
+	return conditions, args
+}
+
+// moviesAfter builds the keyset condition selecting rows strictly after
+// cursor in order: (k1 > $1) OR (k1 = $1 AND k2 > $2) OR ... OR
+// (k1 = $1 AND ... AND m.id > $n), with < for descending keys. Placeholders
+// are numbered after the ones already in args.
+func moviesAfter(order storage.Order, cursor storage.Cursor, args []interface{}) (string, []interface{}, error) {
+	if len(cursor.Values) != len(order) {
+		return "", nil, storage.ErrInvalidCursor
+	}
+
+	first := len(args) + 1
+	for i, key := range order {
+		var value interface{} = cursor.Values[i]
+		var err error
+		switch key.Field {
+		case storage.SortRating:
+			value, err = strconv.Atoi(cursor.Values[i])
+		case storage.SortDate:
+			value, err = time.Parse(time.DateOnly, cursor.Values[i])
+		}
+		if err != nil {
+			return "", nil, storage.ErrInvalidCursor
+		}
+		args = append(args, value)
+	}
+	args = append(args, cursor.Id)
+
+	var clauses []string
+	for i := 0; i <= len(order); i++ {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, movieSortColumns[order[j].Field]+" = $"+strconv.Itoa(first+j))
+		}
+
+		if i < len(order) {
+			after := " > $"
+			if order[i].Desc {
+				after = " < $"
+			}
+			conditions = append(conditions, movieSortColumns[order[i].Field]+after+strconv.Itoa(first+i))
+		} else {
+			conditions = append(conditions, "m.id > $"+strconv.Itoa(first+i))
+		}
+
+		clauses = append(clauses, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+// update builds an UPDATE statement. Column names are always constants
+// from this file, never user input.
+type update struct {
+	sets []string
+	args []interface{}
+}
+
+func (u *update) set(column string, value interface{}) {
+	u.args = append(u.args, value)
+	u.sets = append(u.sets, column+" = $"+strconv.Itoa(len(u.args)))
+}
+
+func (u *update) query(table string, id int64) (string, []interface{}) {
+	args := append(u.args, id)
+
+	return "UPDATE " + table + " SET " + strings.Join(u.sets, ", ") + " WHERE id = $" + strconv.Itoa(len(args)), args
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// placeholders returns the distinct $n numbers used in query, sorted.
+func placeholders(t *testing.T, query string) []int {
+	t.Helper()
+
+	var numbers []int
+	for _, m := range placeholder.FindAllStringSubmatch(query, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(numbers, n) {
+			numbers = append(numbers, n)
+		}
+	}
+	slices.Sort(numbers)
+
+	return numbers
+}
+
+func TestMoviesWherePlaceholders(t *testing.T) {
+	yearFrom, yearTo, ratingMin, ratingMax := 1970, 1990, 6, 8
+	filter := storage.MovieFilter{
+		Title:     "alien",
+		Actor:     "weaver",
+		YearFrom:  &yearFrom,
+		YearTo:    &yearTo,
+		RatingMin: &ratingMin,
+		RatingMax: &ratingMax,
+	}
+
+	conditions, args := moviesWhere(filter, []interface{}{"earlier"})
+	if len(conditions) != 6 {
+		t.Fatalf("%d conditions, want 6", len(conditions))
+	}
+
+	want := []interface{}{"earlier", "%alien%", "%weaver%", 1970, 1990, 6, 8}
+	if !slices.Equal(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+	if got := placeholders(t, strings.Join(conditions, " ")); !slices.Equal(got, []int{2, 3, 4, 5, 6, 7}) {
+		t.Errorf("placeholders = %v, want $2..$7", got)
+	}
+}
+
+func TestMoviesAfter(t *testing.T) {
+	order := storage.Order{{Field: storage.SortRating, Desc: true}, {Field: storage.SortDate}}
+	cursor := storage.Cursor{Sort: order.String(), Values: []string{"8", "1979-05-25"}, Id: 7}
+
+	clause, args, err := moviesAfter(order, cursor, []interface{}{"%alien%", 1970})
+	if err != nil {
+		t.Fatalf("moviesAfter: %v", err)
+	}
+
+	wantClause := "((m.rating < $3) OR (m.rating = $3 AND m.date > $4) OR (m.rating = $3 AND m.date = $4 AND m.id > $5))"
+	if clause != wantClause {
+		t.Errorf("clause = %s\nwant %s", clause, wantClause)
+	}
+
+	date := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+	wantArgs := []interface{}{"%alien%", 1970, 8, date, int64(7)}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("args = %v, want %v", args, wantArgs)
+	}
+	for i := range wantArgs {
+		if got, ok := args[i].(time.Time); ok {
+			if !got.Equal(date) {
+				t.Errorf("args[%d] = %v, want %v", i, got, date)
+			}
+			continue
+		}
+		if args[i] != wantArgs[i] {
+			t.Errorf("args[%d] = %#v, want %#v", i, args[i], wantArgs[i])
+		}
+	}
+}
+
+func TestMoviesAfterInvalidCursor(t *testing.T) {
+	order := storage.Order{{Field: storage.SortRating, Desc: true}, {Field: storage.SortDate}}
+
+	cursors := []storage.Cursor{
+		{Values: []string{"8"}, Id: 1},
+		{Values: []string{"eight", "1979-05-25"}, Id: 1},
+		{Values: []string{"8", "25.05.1979"}, Id: 1},
+	}
+	for _, cursor := range cursors {
+		if _, _, err := moviesAfter(order, cursor, nil); err != storage.ErrInvalidCursor {
+			t.Errorf("moviesAfter(%v): err = %v, want %v", cursor.Values, err, storage.ErrInvalidCursor)
+		}
+	}
+}
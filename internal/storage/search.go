@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+// SearchTerms splits a free-text query into lower-cased words, dropping
+// punctuation and anything a full-text engine would read as an operator.
+func SearchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MatchStart and MatchEnd delimit the hits in a snippet as a backend
+// builds it. They are control characters, which stored text has no business
+// containing, so they survive HTML escaping and MarkSnippet can then turn
+// them into tags.
+const (
+	MatchStart = "\x02"
+	MatchEnd   = "\x03"
+)
+
+var markReplacer = strings.NewReplacer(MatchStart, "<mark>", MatchEnd, "</mark>")
+
+// MarkSnippet HTML-escapes a snippet whose hits are delimited by MatchStart
+// and MatchEnd, then wraps the hits in <mark></mark>. The markup it returns
+// is safe to render: only the tags it adds are live.
+func MarkSnippet(snippet string) string {
+	return markReplacer.Replace(html.EscapeString(snippet))
+}
+
+// ScoreSearch ranks a movie for a search without a full-text index. Every
+// term has to start some word of the title, the cast names or the
+// description, which count 10, 5 and 1 per term. The snippet is the first
+// of those fields, HTML-escaped, with the matching words in <mark></mark>.
+func ScoreSearch(terms []string, title, cast, description string) (float64, string, bool) {
+	fields := []struct {
+		text   string
+		weight float64
+	}{
+		{title, 10},
+		{cast, 5},
+		{description, 1},
+	}
+
+	var score float64
+	for _, term := range terms {
+		matched := false
+		for _, field := range fields {
+			if hasPrefixWord(field.text, term) {
+				score += field.weight
+				matched = true
+			}
+		}
+		if !matched {
+			return 0, "", false
+		}
+	}
+
+	for _, field := range fields {
+		if highlighted, ok := highlight(field.text, terms); ok {
+			return score, highlighted, true
+		}
+	}
+
+	return score, "", true
+}
+
+// RankSearchResults orders results best first, then by id, and keeps at
+// most limit of them.
+func RankSearchResults(results []models.SearchResult, limit int) []models.SearchResult {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Movie.Id < results[j].Movie.Id
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// hasPrefixWord reports whether some word of text starts with term.
+func hasPrefixWord(text, term string) bool {
+	for _, word := range SearchTerms(text) {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlight escapes text and wraps every word that starts with one of terms
+// in <mark></mark>. It reports whether anything was highlighted.
+func highlight(text string, terms []string) (string, bool) {
+	var b strings.Builder
+	found := false
+
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWord(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && isWord(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+
+		hit := false
+		for _, term := range terms {
+			if strings.HasPrefix(strings.ToLower(word), term) {
+				hit = true
+				break
+			}
+		}
+
+		if hit {
+			found = true
+			b.WriteString(MatchStart + word + MatchEnd)
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+
+	return MarkSnippet(b.String()), found
+}
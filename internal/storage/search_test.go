@@ -0,0 +1,41 @@
+package storage
+
+import "testing"
+
+func TestMarkSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"plain", "plain"},
+		{MatchStart + "Alien" + MatchEnd + " crew", "<mark>Alien</mark> crew"},
+		{"<b>" + MatchStart + "bold" + MatchEnd + "</b>", "&lt;b&gt;<mark>bold</mark>&lt;/b&gt;"},
+		{`Tom & "Jerry"`, "Tom &amp; &#34;Jerry&#34;"},
+		{"<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+	}
+
+	for _, tt := range tests {
+		if got := MarkSnippet(tt.snippet); got != tt.want {
+			t.Errorf("MarkSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+func TestScoreSearch(t *testing.T) {
+	score, snippet, ok := ScoreSearch([]string{"ali"}, "Alien <3", "", "")
+	if !ok || score != 10 {
+		t.Fatalf("ScoreSearch = %v, %v, want a title hit worth 10", score, ok)
+	}
+	if want := "<mark>Alien</mark> &lt;3"; snippet != want {
+		t.Errorf("snippet = %q, want %q", snippet, want)
+	}
+
+	score, snippet, ok = ScoreSearch([]string{"weaver", "crew"}, "Alien", "Sigourney Weaver", "The crew")
+	if !ok || score != 6 || snippet != "Sigourney <mark>Weaver</mark>" {
+		t.Errorf("ScoreSearch = %v, %q, %v, want 6 from cast and description, cast snippet", score, snippet, ok)
+	}
+
+	if _, _, ok := ScoreSearch([]string{"alien", "dune"}, "Alien", "", ""); ok {
+		t.Errorf("ScoreSearch matched with a term missing")
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Movie sort fields.
+const (
+	SortTitle  = "title"
+	SortDate   = "date"
+	SortRating = "rating"
+)
+
+// defaultDesc is the direction a field sorts in when none is given: title
+// and date ascending, rating descending.
+var defaultDesc = map[string]bool{
+	SortTitle:  false,
+	SortDate:   false,
+	SortRating: true,
+}
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Order is a list of sort keys applied left to right. Backends always add
+// ascending id as the final tie-breaker, so the result order is total.
+type Order []SortKey
+
+// ParseMovieOrder parses a ?sort= value such as "rating:asc,title" or
+// "date:desc". A field without a direction uses its default one. An empty
+// value means "rating".
+func ParseMovieOrder(sortBy string) (Order, error) {
+	if sortBy == "" {
+		return Order{{Field: SortRating, Desc: true}}, nil
+	}
+
+	var order Order
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(sortBy, ",") {
+		field, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+
+		desc, ok := defaultDesc[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidSort, field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, field)
+		}
+		seen[field] = true
+
+		switch direction {
+		case "":
+		case "asc":
+			desc = false
+		case "desc":
+			desc = true
+		default:
+			return nil, fmt.Errorf("%w: unknown direction %q", ErrInvalidSort, direction)
+		}
+
+		order = append(order, SortKey{Field: field, Desc: desc})
+	}
+
+	return order, nil
+}
+
+// String returns the canonical form, with every direction spelled out.
+func (o Order) String() string {
+	parts := make([]string, 0, len(o))
+	for _, key := range o {
+		direction := "asc"
+		if key.Desc {
+			direction = "desc"
+		}
+		parts = append(parts, key.Field+":"+direction)
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+func TestParseMovieOrder(t *testing.T) {
+	tests := []struct {
+		sortBy  string
+		want    Order
+		wantErr bool
+	}{
+		{sortBy: "", want: Order{{SortRating, true}}},
+		{sortBy: "title", want: Order{{SortTitle, false}}},
+		{sortBy: "date", want: Order{{SortDate, false}}},
+		{sortBy: "rating", want: Order{{SortRating, true}}},
+		{sortBy: "title:desc", want: Order{{SortTitle, true}}},
+		{sortBy: "rating:asc", want: Order{{SortRating, false}}},
+		{sortBy: "rating:asc,title", want: Order{{SortRating, false}, {SortTitle, false}}},
+		{sortBy: "date:desc, title:asc", want: Order{{SortDate, true}, {SortTitle, false}}},
+		{sortBy: "title,date,rating", want: Order{{SortTitle, false}, {SortDate, false}, {SortRating, true}}},
+		{sortBy: "id", wantErr: true},
+		{sortBy: "Title", wantErr: true},
+		{sortBy: "title:up", wantErr: true},
+		{sortBy: "title:", want: Order{{SortTitle, false}}},
+		{sortBy: "title,title:desc", wantErr: true},
+		{sortBy: "title,", wantErr: true},
+		{sortBy: ",", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			got, err := ParseMovieOrder(tt.sortBy)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSort) {
+					t.Fatalf("err = %v, want %v", err, ErrInvalidSort)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderString(t *testing.T) {
+	order, err := ParseMovieOrder("rating:asc,title")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := order.String(), "rating:asc,title:asc"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	again, err := ParseMovieOrder(order.String())
+	if err != nil || !slices.Equal(again, order) {
+		t.Errorf("ParseMovieOrder(%q) = %v, %v, want %v", order.String(), again, err, order)
+	}
+}
+
+func TestMovieCursorRoundTrip(t *testing.T) {
+	order := Order{{SortDate, true}, {SortRating, false}, {SortTitle, false}}
+	movie := models.Movie{
+		Id:     42,
+		Title:  "Brazil",
+		Date:   time.Date(1985, 2, 20, 0, 0, 0, 0, time.UTC),
+		Rating: 8,
+	}
+
+	cursor, err := DecodeCursor(MovieCursor(order, movie).Encode(), order.String())
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+
+	if cursor.Id != movie.Id {
+		t.Errorf("Id = %d, want %d", cursor.Id, movie.Id)
+	}
+	if want := []string{"1985-02-20", "8", "Brazil"}; !slices.Equal(cursor.Values, want) {
+		t.Errorf("Values = %q, want %q", cursor.Values, want)
+	}
+
+	if _, err := DecodeCursor(MovieCursor(order, movie).Encode(), "title:asc"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("other sort: err = %v, want %v", err, ErrInvalidCursor)
+	}
+	if _, err := DecodeCursor("not a cursor", order.String()); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("garbage: err = %v, want %v", err, ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rules;
+DROP TABLE IF EXISTS actors;
+DROP TABLE IF EXISTS movies;
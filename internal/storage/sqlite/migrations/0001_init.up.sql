@@ -0,0 +1,24 @@
+-- Актеры: id, имя, пол, дата рождения
+-- Фильмы: id, название, описание, дата выпуска, рейтинг
+-- Актеры+фильмы: movie_id - actor_id
+--
+-- TODO: поменять id на guid
+--
+-- IF NOT EXISTS lets databases created before migrations adopt this version.
+
+CREATE TABLE IF NOT EXISTS movies(
+	id INTEGER NOT NULL PRIMARY KEY,
+	title TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL,
+	date TEXT NOT NULL,
+	rating INTEGER NOT NULL);
+
+CREATE TABLE IF NOT EXISTS actors(
+	id INTEGER NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	gender TEXT,
+	birthDate TEXT);
+
+CREATE TABLE IF NOT EXISTS rules(
+	movie_id INTEGER NOT NULL,
+	actor_id INTEGER);
@@ -0,0 +1,8 @@
+CREATE TABLE rules_old(
+	movie_id INTEGER NOT NULL,
+	actor_id INTEGER);
+
+INSERT INTO rules_old(movie_id, actor_id) SELECT movie_id, actor_id FROM rules;
+
+DROP TABLE rules;
+ALTER TABLE rules_old RENAME TO rules;
@@ -0,0 +1,16 @@
+-- Orphaned and duplicate links are dropped while copying.
+CREATE TABLE rules_new(
+	movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+	actor_id INTEGER NOT NULL REFERENCES actors(id) ON DELETE CASCADE,
+	PRIMARY KEY (movie_id, actor_id));
+
+INSERT OR IGNORE INTO rules_new(movie_id, actor_id)
+	SELECT r.movie_id, r.actor_id
+	FROM rules r
+	JOIN movies m ON m.id = r.movie_id
+	JOIN actors a ON a.id = r.actor_id;
+
+DROP TABLE rules;
+ALTER TABLE rules_new RENAME TO rules;
+
+CREATE INDEX rules_actor_id ON rules(actor_id);
@@ -0,0 +1,3 @@
+ALTER TABLE rules DROP COLUMN credit_type;
+ALTER TABLE rules DROP COLUMN billing_order;
+ALTER TABLE rules DROP COLUMN character_name;
@@ -0,0 +1,4 @@
+ALTER TABLE rules ADD COLUMN character_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN billing_order INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE rules ADD COLUMN credit_type TEXT NOT NULL DEFAULT 'supporting'
+	CHECK (credit_type IN ('lead', 'supporting', 'cameo', 'voice'));
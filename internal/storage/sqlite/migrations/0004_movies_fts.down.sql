@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS actors_fts_update;
+DROP TRIGGER IF EXISTS rules_fts_delete;
+DROP TRIGGER IF EXISTS rules_fts_insert;
+DROP TRIGGER IF EXISTS movies_fts_delete;
+DROP TRIGGER IF EXISTS movies_fts_update;
+DROP TRIGGER IF EXISTS movies_fts_insert;
+DROP TABLE IF EXISTS movies_fts;
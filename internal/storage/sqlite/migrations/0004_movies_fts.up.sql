@@ -0,0 +1,49 @@
+-- Full-text index over movie titles, descriptions and cast names. The rowid
+-- of movies_fts is the movie id. Needs SQLite built with FTS5.
+CREATE VIRTUAL TABLE movies_fts USING fts5(
+	title,
+	description,
+	actors,
+	tokenize = 'unicode61 remove_diacritics 2');
+
+INSERT INTO movies_fts(rowid, title, description, actors)
+	SELECT m.id, m.title, m.description,
+		coalesce((SELECT group_concat(a.name, ' ')
+			FROM rules r JOIN actors a ON a.id = r.actor_id
+			WHERE r.movie_id = m.id), '')
+	FROM movies m;
+
+CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+	INSERT INTO movies_fts(rowid, title, description, actors)
+	VALUES (new.id, new.title, new.description, '');
+END;
+
+CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, description ON movies BEGIN
+	UPDATE movies_fts SET title = new.title, description = new.description
+	WHERE rowid = new.id;
+END;
+
+CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+	DELETE FROM movies_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER rules_fts_insert AFTER INSERT ON rules BEGIN
+	UPDATE movies_fts SET actors = coalesce((SELECT group_concat(a.name, ' ')
+		FROM rules r JOIN actors a ON a.id = r.actor_id
+		WHERE r.movie_id = new.movie_id), '')
+	WHERE rowid = new.movie_id;
+END;
+
+CREATE TRIGGER rules_fts_delete AFTER DELETE ON rules BEGIN
+	UPDATE movies_fts SET actors = coalesce((SELECT group_concat(a.name, ' ')
+		FROM rules r JOIN actors a ON a.id = r.actor_id
+		WHERE r.movie_id = old.movie_id), '')
+	WHERE rowid = old.movie_id;
+END;
+
+CREATE TRIGGER actors_fts_update AFTER UPDATE OF name ON actors BEGIN
+	UPDATE movies_fts SET actors = coalesce((SELECT group_concat(a.name, ' ')
+		FROM rules r JOIN actors a ON a.id = r.actor_id
+		WHERE r.movie_id = movies_fts.rowid), '')
+	WHERE rowid IN (SELECT movie_id FROM rules WHERE actor_id = new.id);
+END;
@@ -0,0 +1,6 @@
+CREATE TABLE users(
+	id INTEGER NOT NULL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('user', 'admin')),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);
@@ -0,0 +1,5 @@
+CREATE TABLE refresh_tokens(
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);
@@ -1,107 +1,233 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/rmnvlv/golang-cinema-api/internal/models"
-	_ "github.com/rmnvlv/golang-cinema-api/internal/models"
 	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/migrate"
 )
 
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// ftsMigration builds the full-text index. It needs go-sqlite3 compiled
+// with FTS5 (-tags sqlite_fts5) and is skipped otherwise.
+const ftsMigration = 4
+
 type Storage struct {
-	db *sql.DB
+	db       *sql.DB
+	migrator *migrate.Migrator
+	// fts5 tells whether SearchMovies can use the movies_fts index, or has
+	// to fall back to LIKE.
+	fts5 bool
 }
 
-func New(storagePath string) (*Storage, error) {
+var _ storage.Repository = (*Storage)(nil)
 
-	db, err := sql.Open("sqlite3", storagePath)
+// New opens the database and applies any pending migrations. It refuses to
+// start if the database has migrations this binary does not know about.
+func New(storagePath string) (*Storage, error) {
+	s, err := Open(storagePath)
 	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Path", err)
+		return nil, err
 	}
 
-	/*
-		3 таб
-		Актеры: id, имя, пол, дата рождения
-		Фильмы: id, название, описание, дата выпуска, рейтинг
-		Актеры+фильмы: idMovie - idActor
-	*/
-
-	//TODO: поменять id на guid
+	if err := s.migrator.Up(); err != nil {
+		s.db.Close()
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Migrate", err)
+	}
 
-	query, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS movies(
-		id INTEGER NOT NULL PRIMARY KEY,
-		title TEXT NOT NULL UNIQUE,
-		description TEXT NOT NULL,
-		date TEXT NOT NULL,
-		rating INTEGER NOT NULL);
-		`)
+	return s, nil
+}
 
+// Open opens the database without touching its schema.
+func Open(storagePath string) (*Storage, error) {
+	db, err := sql.Open("sqlite3", withForeignKeys(storagePath))
 	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Prepare.Movies", err)
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.Open.Path", err)
+	}
+
+	var fts5 bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.Open.FTS5", err)
 	}
 
-	_, err = query.Exec()
+	migrator, err := migrate.New(db, migrations, "migrations")
 	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Exec.Movies", err)
+		db.Close()
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.Open.Migrator", err)
+	}
+	if !fts5 {
+		migrator = migrator.Without(ftsMigration)
 	}
 
-	query, err = db.Prepare(`
-	CREATE TABLE IF NOT EXISTS actors(
-		id INTEGER NOT NULL PRIMARY KEY,
-		name TEXT NOT NULL,
-		gender TEXT,
-		birthDate TEXT);
-	`)
+	return &Storage{db: db, migrator: migrator, fts5: fts5}, nil
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Prepare.Actors", err)
+// moviesOrderBy renders order as an ORDER BY list ending in m.id.
+func moviesOrderBy(order storage.Order) string {
+	parts := make([]string, 0, len(order)+1)
+	for _, key := range order {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, movieSortColumns[key.Field]+" "+direction)
 	}
 
-	_, err = query.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Exec.Actors", err)
+	return strings.Join(append(parts, "m.id"), ", ")
+}
+
+// moviesWhere renders the filter as conditions on the movies table m.
+func moviesWhere(filter storage.MovieFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Title != "" {
+		conditions = append(conditions, "m.title LIKE ?")
+		args = append(args, "%"+filter.Title+"%")
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, `EXISTS (
+                    SELECT 1 FROM rules r JOIN actors a ON a.id = r.actor_id
+                    WHERE r.movie_id = m.id AND a.name LIKE ?)`)
+		args = append(args, "%"+filter.Actor+"%")
+	}
+	if filter.YearFrom != nil {
+		conditions = append(conditions, "CAST(substr(m.date, 1, 4) AS INTEGER) >= ?")
+		args = append(args, *filter.YearFrom)
+	}
+	if filter.YearTo != nil {
+		conditions = append(conditions, "CAST(substr(m.date, 1, 4) AS INTEGER) <= ?")
+		args = append(args, *filter.YearTo)
+	}
+	if filter.RatingMin != nil {
+		conditions = append(conditions, "m.rating >= ?")
+		args = append(args, *filter.RatingMin)
+	}
+	if filter.RatingMax != nil {
+		conditions = append(conditions, "m.rating <= ?")
+		args = append(args, *filter.RatingMax)
 	}
 
-	query, err = db.Prepare(`
-	CREATE TABLE IF NOT EXISTS rules(
-		movie_id INTEGER NOT NULL,
-		actor_id INTEGER);
-	`)
+	return conditions, args
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Prepare.Rules", err)
+// moviesAfter builds the keyset condition selecting rows strictly after
+// cursor in order: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR
+// (k1 = v1 AND ... AND m.id > id), with < for descending keys.
+func moviesAfter(order storage.Order, cursor storage.Cursor) (string, []interface{}, error) {
+	if len(cursor.Values) != len(order) {
+		return "", nil, storage.ErrInvalidCursor
 	}
 
-	_, err = query.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.New.Exec.Rules", err)
+	values := make([]interface{}, len(order))
+	for i, key := range order {
+		values[i] = cursor.Values[i]
+		if key.Field == storage.SortRating {
+			rating, err := strconv.Atoi(cursor.Values[i])
+			if err != nil {
+				return "", nil, storage.ErrInvalidCursor
+			}
+			values[i] = rating
+		}
+	}
+
+	var clauses []string
+	var args []interface{}
+	for i := 0; i <= len(order); i++ {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, movieSortColumns[order[j].Field]+" = ?")
+			args = append(args, values[j])
+		}
+
+		if i < len(order) {
+			after := " > ?"
+			if order[i].Desc {
+				after = " < ?"
+			}
+			conditions = append(conditions, movieSortColumns[order[i].Field]+after)
+			args = append(args, values[i])
+		} else {
+			conditions = append(conditions, "m.id > ?")
+			args = append(args, cursor.Id)
+		}
+
+		clauses = append(clauses, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+// withForeignKeys turns on foreign key enforcement, which SQLite keeps off
+// by default on every new connection.
+func withForeignKeys(storagePath string) string {
+	if strings.Contains(storagePath, "?") {
+		return storagePath + "&_foreign_keys=on"
+	}
+
+	return storagePath + "?_foreign_keys=on"
+}
+
+// parseDate reads the date part of a nullable TEXT column. NULL and empty
+// values give the zero time.
+func parseDate(value sql.NullString) (time.Time, error) {
+	if !value.Valid || len(value.String) < 10 {
+		return time.Time{}, nil
 	}
 
-	return &Storage{db: db}, nil
+	return time.Parse("2006-01-02", value.String[:10])
+}
+
+func (s *Storage) Migrator() *migrate.Migrator {
+	return s.migrator
+}
+
+// DB exposes the connection pool for health checks and pool statistics.
+func (s *Storage) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
 }
 
 // Actor
-func (s *Storage) CreateActor(name string, gender string, birth time.Time) (int64, error) {
-	query, err := s.db.Prepare("INSERT INTO actors(name, gender, birthDate) VALUES(?, ?, ?)")
+func (s *Storage) CreateActor(ctx context.Context, name string, gender string, birth time.Time) (id int64, err error) {
+	ctx, span := startSpan(ctx, "CreateActor", "insert")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	query, err := s.db.PrepareContext(ctx, "INSERT INTO actors(name, gender, birthDate) VALUES(?, ?, ?)")
 
 	if err != nil {
 		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateActor.Prepare", err)
 	}
 
-	result, err := query.Exec(name, gender, birth)
+	result, err := query.ExecContext(ctx, name, gender, birth)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateActor.Exec", storage.ErrFilmExists)
+			return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateActor.Exec", storage.ErrActorExists)
 		}
 
 		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateActor.Exec", err)
 	}
 
-	id, err := result.LastInsertId()
+	rows = affected(result)
+
+	id, err = result.LastInsertId()
 	if err != nil {
 		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateActor.LastId", err)
 	}
@@ -109,117 +235,259 @@ func (s *Storage) CreateActor(name string, gender string, birth time.Time) (int6
 	return id, nil
 }
 
-func (s *Storage) UpdateActor(actorId int, updates map[string]interface{}) (int64, error) {
-	queryString := "UPDATE actors SET"
+// UpdateActor applies patch and returns the updated actor.
+func (s *Storage) UpdateActor(ctx context.Context, actorId int64, patch storage.ActorPatch) (actor models.Actor, err error) {
+	ctx, span := startSpan(ctx, "UpdateActor", "update")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	var sets []string
 	var args []interface{}
 
-	for k, v := range updates {
-		queryString += " " + k + " = ?,"
-		args = append(args, v)
+	if patch.Name != nil {
+		sets, args = append(sets, "name = ?"), append(args, *patch.Name)
+	}
+	if patch.Gender != nil {
+		sets, args = append(sets, "gender = ?"), append(args, *patch.Gender)
+	}
+	if patch.Birth != nil {
+		sets, args = append(sets, "birthDate = ?"), append(args, *patch.Birth)
 	}
 
-	queryString = queryString[:len(queryString)-1]
-	queryString += "WHERE id = ?"
-	args = append(args, actorId)
+	if len(sets) > 0 {
+		query := "UPDATE actors SET " + strings.Join(sets, ", ") + " WHERE id = ?"
+		result, err := s.db.ExecContext(ctx, query, append(args, actorId)...)
+		if err != nil {
+			if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+				return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor.Exec", storage.ErrActorExists)
+			}
 
-	result, err := s.db.Exec(queryString, args...)
-	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return 0, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor.Exec", storage.ErrFilmExists)
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor.Exec", err)
 		}
 
-		return 0, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor.Exec", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor.LastId", err)
+		n, err := result.RowsAffected()
+		if err != nil {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor.RowsAffected", err)
+		}
+		rows = int(n)
+		if n == 0 {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateActor", storage.ErrActorNotFound)
+		}
 	}
 
-	return id, nil
+	return s.GetActor(ctx, actorId)
 }
 
-func (s *Storage) DeleteActor(actorId int64) error {
-	query, err := s.db.Prepare("DELETE FROM actors WHERE id = ?")
+func (s *Storage) DeleteActor(ctx context.Context, actorId int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteActor", "delete")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	query, err := s.db.PrepareContext(ctx, "DELETE FROM actors WHERE id = ?")
 
 	if err != nil {
 		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteActor.Prepare", err)
 	}
 
-	_, err = query.Exec(actorId)
+	result, err := query.ExecContext(ctx, actorId)
 	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return fmt.Errorf("%s, %w", "storage.sqlite.DeliteActor.Exec", storage.ErrFilmExists)
-		}
-
 		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteActor.Exec", err)
 	}
 
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteActor.RowsAffected", err)
+	}
+	rows = int(n)
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteActor", storage.ErrActorNotFound)
+	}
+
 	return nil
 }
 
-func (s *Storage) GetActors() ([]models.Actor, error) {
+// GetActors returns one page of actors ordered by id with their
+// filmography, plus the cursor of the next page ("" on the last one).
+// Actors without movies come back with an empty Movies slice.
+func (s *Storage) GetActors(ctx context.Context, page storage.Page) (actors []models.Actor, next string, err error) {
+	ctx, span := startSpan(ctx, "GetActors", "select")
+	defer func() { endSpan(span, len(actors), err) }()
+
+	var afterId int64
+	if page.Cursor != "" {
+		cursor, err := storage.DecodeActorCursor(page.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetActors.Cursor", err)
+		}
+		afterId = cursor.Id
+	}
+
+	size := page.Size()
+
 	query := `
-	SELECT a.id, a.name, m.id, m.title
-	FROM actors a
-	JOIN rules r ON a.id = r.actorId
-	JOIN movies m ON r.movieId = m.id
+	SELECT a.id, a.name, a.gender, a.birthDate,
+		m.id, m.title, m.date, m.rating, r.character_name, r.billing_order, r.credit_type
+	FROM (
+		SELECT * FROM actors WHERE id > ? ORDER BY id LIMIT ?
+	) a
+	LEFT JOIN rules r ON a.id = r.actor_id
+	LEFT JOIN movies m ON r.movie_id = m.id
+	ORDER BY a.id, m.date, m.id
 `
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query, afterId, size+1)
 	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetActors.Query", err)
+		return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetActors.Query", err)
 	}
 	defer rows.Close()
 
-	actorsMap := make(map[int64]*models.Actor)
 	for rows.Next() {
 		var actorId int64
 		var actorName string
-		var movieId int64
-		var movieTitle string
-
-		err := rows.Scan(&actorId, &actorName, &movieId, &movieTitle)
+		var actorGender sql.NullString
+		var actorBirth sql.NullString
+		var movieId sql.NullInt64
+		var movieTitle sql.NullString
+		var movieDate sql.NullString
+		var movieRating sql.NullInt64
+		var character sql.NullString
+		var billingOrder sql.NullInt64
+		var creditType sql.NullString
+
+		err := rows.Scan(&actorId, &actorName, &actorGender, &actorBirth,
+			&movieId, &movieTitle, &movieDate, &movieRating, &character, &billingOrder, &creditType)
 		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetActors.Scan", err)
+			return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetActors.Scan", err)
 		}
 
-		actor, ok := actorsMap[actorId]
-		if !ok {
-			actor = &models.Actor{
+		if len(actors) == 0 || actors[len(actors)-1].Id != actorId {
+			birth, err := parseDate(actorBirth)
+			if err != nil {
+				return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetActors.DateConvert", err)
+			}
+
+			actors = append(actors, models.Actor{
 				Id:     actorId,
 				Name:   actorName,
-				Movies: []string{},
-			}
-			actorsMap[actorId] = actor
+				Gender: actorGender.String,
+				Birth:  birth,
+				Movies: []models.MovieSummary{},
+			})
+		}
+
+		if !movieId.Valid {
+			continue
+		}
+
+		date, err := parseDate(movieDate)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetActors.DateConvert", err)
 		}
 
-		actor.Movies = append(actor.Movies, fmt.Sprint(models.Movie{Id: movieId, Title: movieTitle}))
+		actor := &actors[len(actors)-1]
+		actor.Movies = append(actor.Movies, models.MovieSummary{
+			Id:     movieId.Int64,
+			Title:  movieTitle.String,
+			Date:   date,
+			Rating: int(movieRating.Int64),
+			Role: models.Role{
+				Character:    character.String,
+				BillingOrder: int(billingOrder.Int64),
+				CreditType:   models.CreditType(creditType.String),
+			},
+		})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetActors.rowsErr", err)
+		return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetActors.rowsErr", err)
+	}
+
+	if actors == nil {
+		actors = []models.Actor{}
 	}
 
-	actors := make([]models.Actor, 0, len(actorsMap))
-	for _, actor := range actorsMap {
-		actors = append(actors, *actor)
+	if len(actors) > size {
+		actors = actors[:size]
+		next = storage.ActorCursor(actors[size-1]).Encode()
 	}
 
-	return actors, nil
+	return actors, next, nil
+}
+
+func (s *Storage) GetActor(ctx context.Context, actorId int64) (actor models.Actor, err error) {
+	ctx, span := startSpan(ctx, "GetActor", "select")
+	defer func() { endSpan(span, 1, err) }()
+
+	var gender sql.NullString
+	var birthString sql.NullString
+
+	err = s.db.QueryRowContext(ctx, "SELECT id, name, gender, birthDate FROM actors WHERE id = ?", actorId).
+		Scan(&actor.Id, &actor.Name, &gender, &birthString)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.Scan", storage.ErrActorNotFound)
+		}
+
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.Scan", err)
+	}
+
+	actor.Gender = gender.String
+	actor.Birth, err = parseDate(birthString)
+	if err != nil {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.DateConvert", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT m.id, m.title, m.date, m.rating, r.character_name, r.billing_order, r.credit_type
+	FROM movies m
+	JOIN rules r ON m.id = r.movie_id
+	WHERE r.actor_id = ?
+	ORDER BY m.date, m.id
+`, actorId)
+	if err != nil {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.Query", err)
+	}
+	defer rows.Close()
+
+	actor.Movies = []models.MovieSummary{}
+	for rows.Next() {
+		var movie models.MovieSummary
+		var dateString string
+
+		if err := rows.Scan(&movie.Id, &movie.Title, &dateString, &movie.Rating,
+			&movie.Role.Character, &movie.Role.BillingOrder, &movie.Role.CreditType); err != nil {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.RowsScan", err)
+		}
+
+		movie.Date, err = time.Parse("2006-01-02", dateString[:10])
+		if err != nil {
+			return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.DateConvert", err)
+		}
+
+		actor.Movies = append(actor.Movies, movie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Actor{}, fmt.Errorf("%s, %w", "storage.sqlite.GetActor.RowsError", err)
+	}
+
+	return actor, nil
 }
 
 //Movie
 
-func (s *Storage) CreateMovie(title string, description string, date time.Time, rating int8) (int64, error) {
+func (s *Storage) CreateMovie(ctx context.Context, title string, description string, date time.Time, rating int8) (id int64, err error) {
+	ctx, span := startSpan(ctx, "CreateMovie", "insert")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
 
-	query, err := s.db.Prepare("INSERT INTO movies(title, description, date, rating) VALUES(?, ?, ?, ?)")
+	query, err := s.db.PrepareContext(ctx, "INSERT INTO movies(title, description, date, rating) VALUES(?, ?, ?, ?)")
 
 	if err != nil {
 		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateMovie.Prepare", err)
 	}
 
-	result, err := query.Exec(title, description, date, rating)
+	result, err := query.ExecContext(ctx, title, description, date, rating)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 			return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateMovie.Exec", storage.ErrFilmExists)
@@ -228,7 +496,9 @@ func (s *Storage) CreateMovie(title string, description string, date time.Time,
 		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateMovie.Exec", err)
 	}
 
-	id, err := result.LastInsertId()
+	rows = affected(result)
+
+	id, err = result.LastInsertId()
 	if err != nil {
 		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateMovie.LastId", err)
 	}
@@ -236,91 +506,145 @@ func (s *Storage) CreateMovie(title string, description string, date time.Time,
 	return id, nil
 }
 
-func (s *Storage) UpdateMovie(filmId int, updates map[string]interface{}) (int64, error) {
-	queryString := "UPDATE movies SET"
+// UpdateMovie applies patch and returns the updated movie.
+func (s *Storage) UpdateMovie(ctx context.Context, filmId int64, patch storage.MoviePatch) (movie models.Movie, err error) {
+	ctx, span := startSpan(ctx, "UpdateMovie", "update")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	var sets []string
 	var args []interface{}
 
-	for k, v := range updates {
-		queryString += " " + k + " = ?,"
-		args = append(args, v)
+	if patch.Title != nil {
+		sets, args = append(sets, "title = ?"), append(args, *patch.Title)
+	}
+	if patch.Description != nil {
+		sets, args = append(sets, "description = ?"), append(args, *patch.Description)
+	}
+	if patch.Date != nil {
+		sets, args = append(sets, "date = ?"), append(args, *patch.Date)
+	}
+	if patch.Rating != nil {
+		sets, args = append(sets, "rating = ?"), append(args, *patch.Rating)
 	}
 
-	queryString = queryString[:len(queryString)-1]
-	queryString += "WHERE id = ?"
-	args = append(args, filmId)
+	if len(sets) > 0 {
+		query := "UPDATE movies SET " + strings.Join(sets, ", ") + " WHERE id = ?"
+		result, err := s.db.ExecContext(ctx, query, append(args, filmId)...)
+		if err != nil {
+			if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+				return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie.Exec", storage.ErrFilmExists)
+			}
 
-	result, err := s.db.Exec(queryString, args...)
-	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return 0, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie.Exec", storage.ErrFilmExists)
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie.Exec", err)
 		}
 
-		return 0, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie.Exec", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie.LastId", err)
+		n, err := result.RowsAffected()
+		if err != nil {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie.RowsAffected", err)
+		}
+		rows = int(n)
+		if n == 0 {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.UpdateMovie", storage.ErrMovieNotFound)
+		}
 	}
 
-	return id, nil
+	return s.GetMovie(ctx, filmId)
 }
 
-func (s *Storage) DeliteMovie(filmId int) error {
-	query, err := s.db.Prepare("DELETE FROM movies WHERE id = ?")
+func (s *Storage) DeliteMovie(ctx context.Context, filmId int) (err error) {
+	ctx, span := startSpan(ctx, "DeliteMovie", "delete")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	query, err := s.db.PrepareContext(ctx, "DELETE FROM movies WHERE id = ?")
 
 	if err != nil {
 		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteMovie.Prepare", err)
 	}
 
-	_, err = query.Exec(filmId)
+	result, err := query.ExecContext(ctx, filmId)
 	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return fmt.Errorf("%s, %w", "storage.sqlite.DeliteMovie.Exec", storage.ErrFilmExists)
-		}
-
 		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteMovie.Exec", err)
 	}
 
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteMovie.RowsAffected", err)
+	}
+	rows = int(n)
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeliteMovie", storage.ErrMovieNotFound)
+	}
+
 	return nil
 }
 
-func (s *Storage) GetMoviesSorted(sortBy string) ([]models.Movie, error) {
-	var query string
-	switch sortBy {
-	case "title":
-		query = `
-            SELECT m.id, m.title, m.rating, m.description, m.date, m.rating,  a.id, a.name, a.gender
-            FROM movies m
-            LEFT JOIN rules r ON m.id = r.movieId
-            LEFT JOIN actors a ON r.actorId = a.id
-            ORDER BY m.title ASC
-        `
-	case "date":
-		query = `
-            SELECT m.id, m.title, m.rating, m.description, m.date, m.rating,  a.id, a.name, a.gender
-            FROM movies m
-            LEFT JOIN rules r ON m.id = r.movieId
-            LEFT JOIN actors a ON r.actorId = a.id
-            ORDER BY m.date ASC
-        `
-	default:
-		query = `
-            SELECT m.id, m.title, m.description, m.date, m.rating,  a.id, a.name, a.gender
-            FROM movies m
-            LEFT JOIN rules r ON m.id = r.movieId
-            LEFT JOIN actors a ON r.actorId = a.id
-            ORDER BY m.rating DESC
-        `
+// movieSortColumns maps sort fields to the expression a page is ordered
+// by. Dates are compared on their YYYY-MM-DD prefix since the column is TEXT.
+var movieSortColumns = map[string]string{
+	storage.SortTitle:  "m.title",
+	storage.SortDate:   "substr(m.date, 1, 10)",
+	storage.SortRating: "m.rating",
+}
+
+// GetMoviesSorted returns one page of movies matching filter in the order
+// described by sortBy (see storage.ParseMovieOrder) with id as the final
+// tie-breaker, plus the cursor of the next page ("" on the last one).
+func (s *Storage) GetMoviesSorted(ctx context.Context, filter storage.MovieFilter, sortBy string, page storage.Page) (movies []models.Movie, next string, err error) {
+	ctx, span := startSpan(ctx, "GetMoviesSorted", "select")
+	defer func() { endSpan(span, len(movies), err) }()
+
+	order, err := storage.ParseMovieOrder(sortBy)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Order", err)
+	}
+
+	conditions, args := moviesWhere(filter)
+	if page.Cursor != "" {
+		cursor, err := storage.DecodeCursor(page.Cursor, order.String())
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Cursor", err)
+		}
+
+		after, afterArgs, err := moviesAfter(order, cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Cursor", err)
+		}
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
 	}
 
-	rows, err := s.db.Query(query)
+	var where string
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	size := page.Size()
+	args = append(args, size+1)
+
+	orderBy := moviesOrderBy(order)
+	query := `
+            SELECT m.id, m.title, m.description, m.date, m.rating, a.id, a.name, a.gender,
+                r.character_name, r.billing_order, r.credit_type
+            FROM (
+                SELECT * FROM movies m
+                ` + where + `
+                ORDER BY ` + orderBy + `
+                LIMIT ?
+            ) m
+            LEFT JOIN rules r ON m.id = r.movie_id
+            LEFT JOIN actors a ON r.actor_id = a.id
+            ORDER BY ` + orderBy + `, r.billing_order, a.id
+        `
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Query", err)
+		return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Query", err)
 	}
 	defer rows.Close()
 
-	var moviesMap = make(map[int64]*models.Movie)
+	movies = make([]models.Movie, 0, size+1)
 	for rows.Next() {
 		var movieID int64
 		var movieTitle string
@@ -329,138 +653,353 @@ func (s *Storage) GetMoviesSorted(sortBy string) ([]models.Movie, error) {
 		var movieRating int
 		var actorID sql.NullInt64
 		var actorName sql.NullString
-		var actorGender string
+		var actorGender sql.NullString
+		var character sql.NullString
+		var billingOrder sql.NullInt64
+		var creditType sql.NullString
 
 		err := rows.Scan(&movieID, &movieTitle, &movieDescription,
-			&movieDateString, &movieRating, &actorID, &actorName, &actorGender)
+			&movieDateString, &movieRating, &actorID, &actorName, &actorGender,
+			&character, &billingOrder, &creditType)
 		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Scan", err)
+			return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.Scan", err)
 		}
 
-		movieDate, err := time.Parse("2006-01-02", movieDateString[:10])
-		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.DateConvert", err)
-		}
+		if len(movies) == 0 || movies[len(movies)-1].Id != movieID {
+			movieDate, err := time.Parse("2006-01-02", movieDateString[:10])
+			if err != nil {
+				return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.DateConvert", err)
+			}
 
-		movie, ok := moviesMap[movieID]
-		if !ok {
-			movie = &models.Movie{
+			movies = append(movies, models.Movie{
 				Id:          movieID,
 				Title:       movieTitle,
 				Description: movieDescription,
 				Date:        movieDate,
 				Rating:      movieRating,
-				Actors:      make([]models.Actor, 0),
-			}
-			moviesMap[movieID] = movie
+				Actors:      make([]models.CastMember, 0),
+			})
 		}
 
 		if actorID.Valid && actorName.Valid {
-			movie.Actors = append(movie.Actors, models.Actor{Id: actorID.Int64, Name: actorName.String})
+			movie := &movies[len(movies)-1]
+			movie.Actors = append(movie.Actors, models.CastMember{
+				Id:     actorID.Int64,
+				Name:   actorName.String,
+				Gender: actorGender.String,
+				Role: models.Role{
+					Character:    character.String,
+					BillingOrder: int(billingOrder.Int64),
+					CreditType:   models.CreditType(creditType.String),
+				},
+			})
 		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.RowsErr", err)
+		return nil, "", fmt.Errorf("%s, %w", "storage.sqlite.GetMovies.RowsErr", err)
 	}
 
-	var movies = make([]models.Movie, 0, len(moviesMap))
-	for _, movie := range moviesMap {
-		movies = append(movies, *movie)
+	if len(movies) > size {
+		movies = movies[:size]
+		next = storage.MovieCursor(order, movies[size-1]).Encode()
 	}
 
-	return movies, nil
+	return movies, next, nil
 }
 
-func (s *Storage) GetMovieByFragment(fragmentType string, fragment string) ([]models.Movie, error) {
+func (s *Storage) GetMovie(ctx context.Context, filmId int64) (movie models.Movie, err error) {
+	ctx, span := startSpan(ctx, "GetMovie", "select")
+	defer func() { endSpan(span, 1, err) }()
+
+	var dateString string
+
+	err = s.db.QueryRowContext(ctx, "SELECT id, title, description, date, rating FROM movies WHERE id = ?", filmId).
+		Scan(&movie.Id, &movie.Title, &movie.Description, &dateString, &movie.Rating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.GetMovie.Scan", storage.ErrMovieNotFound)
+		}
+
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.GetMovie.Scan", err)
+	}
+
+	movie.Date, err = time.Parse("2006-01-02", dateString[:10])
+	if err != nil {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.GetMovie.DateConvert", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT a.id, a.name, a.gender, r.character_name, r.billing_order, r.credit_type
+	FROM actors a
+	JOIN rules r ON a.id = r.actor_id
+	WHERE r.movie_id = ?
+	ORDER BY r.billing_order, a.id
+`, filmId)
+	if err != nil {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.GetMovie.Query", err)
+	}
+	defer rows.Close()
+
+	movie.Actors = make([]models.CastMember, 0)
+	for rows.Next() {
+		var actor models.CastMember
+		var gender sql.NullString
+
+		if err := rows.Scan(&actor.Id, &actor.Name, &gender,
+			&actor.Role.Character, &actor.Role.BillingOrder, &actor.Role.CreditType); err != nil {
+			return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.GetMovie.RowsScan", err)
+		}
+		actor.Gender = gender.String
+
+		movie.Actors = append(movie.Actors, actor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Movie{}, fmt.Errorf("%s, %w", "storage.sqlite.GetMovie.RowsError", err)
+	}
+
+	return movie, nil
+}
+
+// GetMovieByFragment finds movies whose title or cast member name contains
+// fragment. With fuzzy set, typos and Cyrillic/Latin transliterations are
+// tolerated too, except in the first letter of a word. Those can't be
+// expressed in SQL, so rows are narrowed down to the ones with a word
+// starting with each term's initials, and every one of them is scored by
+// storage.MatchScore before the best limit are kept.
+func (s *Storage) GetMovieByFragment(ctx context.Context, fragmentType string, fragment string, fuzzy bool, limit int) (results []models.SearchResult, err error) {
+	ctx, span := startSpan(ctx, "GetMovieByFragment", "select")
+	defer func() { endSpan(span, len(results), err) }()
+
+	var query, column, ftsColumn string
 	switch fragmentType {
 	case "title":
-		movies, err := s.searchMoviesByTitle(fmt.Sprint(fragment))
-		return movies, err
+		query = `
+        SELECT m.id, m.title, m.description, m.date, m.rating, m.title
+        FROM movies m
+    `
+		column, ftsColumn = "m.title", "title"
 	case "actor":
-		movies, err := s.searchMoviesByActor(fmt.Sprint(fragment))
-		return movies, err
+		query = `
+        SELECT m.id, m.title, m.description, m.date, m.rating, a.name
+        FROM movies m
+        JOIN rules r ON m.id = r.movie_id
+        JOIN actors a ON a.id = r.actor_id
+    `
+		column, ftsColumn = "a.name", "actors"
+	default:
+		return nil, fmt.Errorf("%s %q, %w", "storage.sqlite.GetMovieByFragment", fragmentType, storage.ErrInvalidFilter)
+	}
+
+	var args []interface{}
+	if fuzzy {
+		initials := storage.FuzzyInitials(fragment)
+		if len(initials) == 0 {
+			return []models.SearchResult{}, nil
+		}
+
+		var conditions []string
+		if s.fts5 {
+			conditions = append(conditions, "m.id IN (SELECT rowid FROM movies_fts WHERE movies_fts MATCH ?)")
+			args = append(args, fuzzyMatch(ftsColumn, initials))
+		}
+		for _, letters := range initials {
+			conditions = append(conditions, "("+column+" GLOB ? OR "+column+" GLOB ?)")
+			args = append(args, "["+letters+"]*", "*"+wordBreak+"["+letters+"]*")
+		}
+
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	} else {
+		query += " WHERE " + column + " LIKE ?"
+		args = append(args, "%"+fragment+"%")
 	}
 
-	return nil, fmt.Errorf("%s", "storage.sqlite.searchMoviesByFragment.NotEnoughtFragments")
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovieByFragment.Query", err)
+	}
+	defer rows.Close()
+
+	matches := storage.NewFragmentMatches(fragment, fuzzy)
+	for rows.Next() {
+		var movie models.Movie
+		var date sql.NullString
+		var text string
+
+		err := rows.Scan(&movie.Id, &movie.Title, &movie.Description, &date, &movie.Rating, &text)
+		if err != nil {
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovieByFragment.RowsScan", err)
+		}
+		if movie.Date, err = parseDate(date); err != nil {
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovieByFragment.DateConvert", err)
+		}
+
+		matches.Add(movie, text)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.GetMovieByFragment.RowsError", err)
+	}
+
+	return matches.Results(limit), nil
 }
 
-func (s *Storage) searchMoviesByTitle(fragment string) ([]models.Movie, error) {
+// SearchMovies runs a full-text query over titles, descriptions and cast
+// names. Every word of q must match, as a prefix. Results are ranked by
+// bm25 with title hits weighted highest, then cast, then description.
+func (s *Storage) SearchMovies(ctx context.Context, q string, limit int) (results []models.SearchResult, err error) {
+	ctx, span := startSpan(ctx, "SearchMovies", "select")
+	defer func() { endSpan(span, len(results), err) }()
+
+	terms := storage.SearchTerms(q)
+	if len(terms) == 0 {
+		return []models.SearchResult{}, nil
+	}
+
+	if !s.fts5 {
+		return s.searchLike(ctx, terms, limit)
+	}
+
+	match := make([]string, 0, len(terms))
+	for _, term := range terms {
+		match = append(match, `"`+term+`"*`)
+	}
+
 	query := `
-        SELECT *
-        FROM movies m
-        WHERE m.title LIKE ?
+        SELECT m.id, m.title, m.description, m.date, m.rating,
+            snippet(movies_fts, -1, ?, ?, '…', 16),
+            bm25(movies_fts, 10.0, 1.0, 5.0) AS rank
+        FROM movies_fts
+        JOIN movies m ON m.id = movies_fts.rowid
+        WHERE movies_fts MATCH ?
+        ORDER BY rank, m.id
+        LIMIT ?
     `
-	rows, err := s.db.Query(query, "%"+fragment+"%")
+	rows, err := s.db.QueryContext(ctx, query, storage.MatchStart, storage.MatchEnd, strings.Join(match, " "), limit)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.SearchMovies.Query", err)
 	}
 	defer rows.Close()
 
-	var movies []models.Movie
+	results = []models.SearchResult{}
 	for rows.Next() {
-		var movie models.Movie
-		timeString := ""
-		err := rows.Scan(&movie.Id, &movie.Title, &movie.Description, &timeString, &movie.Rating)
+		var result models.SearchResult
+		var dateString string
+		var rank float64
+
+		err := rows.Scan(&result.Movie.Id, &result.Movie.Title, &result.Movie.Description,
+			&dateString, &result.Movie.Rating, &result.Snippet, &rank)
 		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchMoviesByTitle.RowsScan", err)
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.SearchMovies.RowsScan", err)
 		}
-		date, err := time.Parse("2006-01-02", timeString[:10])
+
+		result.Movie.Date, err = time.Parse("2006-01-02", dateString[:10])
 		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchMoviesByTitle.DateConvert", err)
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.SearchMovies.DateConvert", err)
 		}
-		movie.Date = date
-		movies = append(movies, movie)
+		// bm25 is lower-is-better and negative; flip it for clients.
+		result.Score = -rank
+		result.Snippet = storage.MarkSnippet(result.Snippet)
+
+		results = append(results, result)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchMoviesByTitle.RowsError", err)
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.SearchMovies.RowsError", err)
 	}
 
-	return movies, nil
+	return results, nil
 }
 
-func (s *Storage) searchMoviesByActor(fragment string) ([]models.Movie, error) {
+// wordBreak is a GLOB class for a character that can end a word. It is
+// looser than storage.SearchTerms, which splits on anything that is not a
+// letter or digit, so a word start it misses is never a real one.
+const wordBreak = "[^0-9A-Za-zЁА-яё]"
+
+// fuzzyMatch builds an FTS5 query for rows where column has, for every
+// element of initials, a word starting with one of its letters.
+func fuzzyMatch(column string, initials []string) string {
+	groups := make([]string, 0, len(initials))
+	for _, letters := range initials {
+		lower := []rune(strings.ToLower(letters))
+		slices.Sort(lower)
+
+		var prefixes []string
+		for _, letter := range slices.Compact(lower) {
+			prefixes = append(prefixes, `"`+string(letter)+`"*`)
+		}
+		groups = append(groups, column+" : ("+strings.Join(prefixes, " OR ")+")")
+	}
+
+	return strings.Join(groups, " AND ")
+}
+
+// searchLike serves SearchMovies without the FTS5 index: LIKE narrows the
+// movies down to those containing every term, and storage.ScoreSearch
+// ranks them the way the memory backend does.
+func (s *Storage) searchLike(ctx context.Context, terms []string, limit int) ([]models.SearchResult, error) {
+	var conditions []string
+	var args []interface{}
+	for _, term := range terms {
+		conditions = append(conditions, "(title LIKE ? OR description LIKE ? OR cast_names LIKE ?)")
+		pattern := "%" + term + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+
 	query := `
-        SELECT m.id, m.title, m.description, m.date, m.rating
-        FROM movies m
-        JOIN rules r ON m.id = r.movieId
-        JOIN actors a ON a.id = r.actorId
-        WHERE a.name LIKE ?
-    `
-	rows, err := s.db.Query(query, "%"+fragment+"%")
+        SELECT id, title, description, date, rating, cast_names
+        FROM (
+            SELECT m.id, m.title, m.description, m.date, m.rating,
+                coalesce((SELECT group_concat(a.name, ' ')
+                    FROM rules r JOIN actors a ON a.id = r.actor_id
+                    WHERE r.movie_id = m.id), '') AS cast_names
+            FROM movies m
+        )
+        WHERE ` + strings.Join(conditions, " AND ")
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchLike.Query", err)
 	}
 	defer rows.Close()
 
-	var movies []models.Movie
+	results := []models.SearchResult{}
 	for rows.Next() {
 		var movie models.Movie
-		timeString := ""
-		err := rows.Scan(&movie.Id, &movie.Title, &movie.Description, &timeString, &movie.Rating)
+		var dateString, castNames string
+
+		err := rows.Scan(&movie.Id, &movie.Title, &movie.Description, &dateString, &movie.Rating, &castNames)
 		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchMoviesByActor.RowsScan", err)
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchLike.RowsScan", err)
 		}
-		date, err := time.Parse("2006-01-02", timeString[:10])
+
+		movie.Date, err = time.Parse("2006-01-02", dateString[:10])
 		if err != nil {
-			return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchMoviesByActor.DateConvert", err)
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchLike.DateConvert", err)
 		}
-		movie.Date = date
-		movies = append(movies, movie)
+
+		score, snippet, ok := storage.ScoreSearch(terms, movie.Title, castNames, movie.Description)
+		if !ok {
+			continue
+		}
+		results = append(results, models.SearchResult{Movie: movie, Snippet: snippet, Score: score})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchMoviesByActor.RowsError", err)
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.searchLike.RowsError", err)
 	}
 
-	return movies, nil
+	return storage.RankSearchResults(results, limit), nil
 }
 
 //Rules
 
-func (s *Storage) CreateRule(novieId int, actorIds []int) error {
-	tx, err := s.db.Begin()
+func (s *Storage) CreateRule(ctx context.Context, movieId int, cast []models.CastEntry) (err error) {
+	ctx, span := startSpan(ctx, "CreateRule", "insert")
+	defer func() { endSpan(span, len(cast), err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("%s, %w", "storage.sqlite.CreateRule.txBegin", err)
 	}
@@ -473,12 +1012,350 @@ func (s *Storage) CreateRule(novieId int, actorIds []int) error {
 		err = tx.Commit()
 	}()
 
-	for _, acactorId := range actorIds {
-		_, err = tx.Exec("INSERT INTO rules (movie_id, actor_id) VALUES(?, ?)", novieId, acactorId)
+	if err = insertRules(ctx, tx, int64(movieId), cast); err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.CreateRule", err)
+	}
+
+	return nil
+}
+
+// ReplaceRules swaps the whole cast of a movie in one transaction.
+func (s *Storage) ReplaceRules(ctx context.Context, movieId int64, cast []models.CastEntry) (err error) {
+	ctx, span := startSpan(ctx, "ReplaceRules", "replace")
+	defer func() { endSpan(span, len(cast), err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.ReplaceRules.txBegin", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM rules WHERE movie_id = ?", movieId); err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.ReplaceRules.Delete", err)
+	}
+
+	if err = insertRules(ctx, tx, movieId, cast); err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.ReplaceRules", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteRules(ctx context.Context, movieId int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteRules", "delete")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	query, err := s.db.PrepareContext(ctx, "DELETE FROM rules WHERE movie_id = ?")
+
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeleteRules.Prepare", err)
+	}
+
+	result, err := query.ExecContext(ctx, movieId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeleteRules.Exec", err)
+	}
+	rows = affected(result)
+
+	// No links deleted: an empty cast, or no such movie.
+	if rows == 0 {
+		var exists int
+		err = s.db.QueryRowContext(ctx, "SELECT 1 FROM movies WHERE id = ?", movieId).Scan(&exists)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s, %w", "storage.sqlite.DeleteRules.Movie", storage.ErrMovieNotFound)
+			}
+
+			return fmt.Errorf("%s, %w", "storage.sqlite.DeleteRules.Movie", err)
+		}
+	}
+
+	return nil
+}
+
+// insertRules links the cast entries to movieId inside tx. The foreign keys would
+// reject unknown ids anyway, but SQLite does not say which side is missing,
+// so both are checked up front to return a typed error.
+func insertRules(ctx context.Context, tx *sql.Tx, movieId int64, cast []models.CastEntry) error {
+	var exists int
+
+	err := tx.QueryRowContext(ctx, "SELECT 1 FROM movies WHERE id = ?", movieId).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", "insertRules.Movie", storage.ErrMovieNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", "insertRules.Movie", err)
+	}
+
+	for _, entry := range cast {
+		actorId := entry.ActorId
+		creditType := entry.CreditType
+		if creditType == "" {
+			creditType = models.CreditSupporting
+		}
+
+		err := tx.QueryRowContext(ctx, "SELECT 1 FROM actors WHERE id = ?", actorId).Scan(&exists)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s %d, %w", "insertRules.Actor", actorId, storage.ErrActorNotFound)
+			}
+
+			return fmt.Errorf("%s, %w", "insertRules.Actor", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO rules (movie_id, actor_id, character_name, billing_order, credit_type)
+		VALUES(?, ?, ?, ?, ?)`,
+			movieId, actorId, entry.Character, entry.BillingOrder, creditType)
+		if err != nil {
+			if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+				return fmt.Errorf("%s %d, %w", "insertRules.Exec", actorId, storage.ErrRuleExists)
+			}
+
+			return fmt.Errorf("%s, %w", "insertRules.Exec", err)
+		}
+	}
+
+	return nil
+}
+
+//Users
+
+func (s *Storage) CreateUser(ctx context.Context, username string, passwordHash string, role models.UserRole) (id int64, err error) {
+	ctx, span := startSpan(ctx, "CreateUser", "insert")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	result, err := s.db.ExecContext(ctx, "INSERT INTO users(username, password_hash, role) VALUES(?, ?, ?)",
+		username, passwordHash, role)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateUser.Exec", storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateUser.Exec", err)
+	}
+
+	rows = affected(result)
+
+	id, err = result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateUser.LastId", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, username string) (user models.User, err error) {
+	ctx, span := startSpan(ctx, "GetUser", "select")
+	defer func() { endSpan(span, 1, err) }()
+
+	err = s.db.QueryRowContext(ctx, "SELECT id, username, password_hash, role FROM users WHERE username = ?", username).
+		Scan(&user.Id, &user.Username, &user.PasswordHash, &user.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s, %w", "storage.sqlite.GetUser.Scan", storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s, %w", "storage.sqlite.GetUser.Scan", err)
+	}
+
+	return user, nil
+}
+
+//Refresh tokens
+
+func (s *Storage) CreateRefreshToken(ctx context.Context, tokenId string, userId int64, expiresAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "CreateRefreshToken", "insert")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at < ?", time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.CreateRefreshToken.Purge", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, "INSERT INTO refresh_tokens(id, user_id, expires_at) VALUES(?, ?, ?)",
+		tokenId, userId, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.CreateRefreshToken.Exec", err)
+	}
+	rows = affected(result)
+
+	return nil
+}
+
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenId string) (err error) {
+	ctx, span := startSpan(ctx, "UseRefreshToken", "delete")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE id = ? AND expires_at >= ?",
+		tokenId, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.UseRefreshToken.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.UseRefreshToken.RowsAffected", err)
+	}
+	rows = int(n)
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.sqlite.UseRefreshToken", storage.ErrRefreshTokenNotFound)
+	}
+
+	return nil
+}
+
+//API keys
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key models.APIKey) (id int64, err error) {
+	ctx, span := startSpan(ctx, "CreateAPIKey", "insert")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	result, err := s.db.ExecContext(ctx, "INSERT INTO api_keys(name, prefix, key_hash, scopes, expires_at) VALUES(?, ?, ?, ?, ?)",
+		key.Name, key.Prefix, key.Hash, storage.JoinScopes(key.Scopes), key.ExpiresAt)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateAPIKey.Exec", storage.ErrAPIKeyExists)
+		}
+
+		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateAPIKey.Exec", err)
+	}
+
+	rows = affected(result)
+
+	id, err = result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", "storage.sqlite.CreateAPIKey.LastId", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKey(ctx context.Context, prefix string) (key models.APIKey, err error) {
+	ctx, span := startSpan(ctx, "GetAPIKey", "select")
+	defer func() { endSpan(span, 1, err) }()
+
+	row := s.db.QueryRowContext(ctx, `SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+		FROM api_keys WHERE prefix = ?`, prefix)
+
+	key, err = scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("%s, %w", "storage.sqlite.GetAPIKey.Scan", storage.ErrAPIKeyNotFound)
+		}
+
+		return models.APIKey{}, fmt.Errorf("%s, %w", "storage.sqlite.GetAPIKey.Scan", err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) (keys []models.APIKey, err error) {
+	ctx, span := startSpan(ctx, "ListAPIKeys", "select")
+	defer func() { endSpan(span, len(keys), err) }()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+		FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.ListAPIKeys.Query", err)
+	}
+	defer rows.Close()
+
+	keys = []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
 		if err != nil {
-			return fmt.Errorf("%s, %w", "storage.sqlite.CreateRule.txFor", err)
+			return nil, fmt.Errorf("%s, %w", "storage.sqlite.ListAPIKeys.Scan", err)
 		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", "storage.sqlite.ListAPIKeys.Rows", err)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) DeleteAPIKey(ctx context.Context, keyId int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteAPIKey", "delete")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = ?", keyId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeleteAPIKey.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeleteAPIKey.RowsAffected", err)
+	}
+	rows = int(n)
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.sqlite.DeleteAPIKey", storage.ErrAPIKeyNotFound)
 	}
 
 	return nil
 }
+
+func (s *Storage) TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "TouchAPIKey", "update")
+	var rows int
+	defer func() { endSpan(span, rows, err) }()
+
+	result, err := s.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", usedAt, keyId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.TouchAPIKey.Exec", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", "storage.sqlite.TouchAPIKey.RowsAffected", err)
+	}
+	rows = int(n)
+	if n == 0 {
+		return fmt.Errorf("%s, %w", "storage.sqlite.TouchAPIKey", storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey reads one api_keys row selected in the column order used above.
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&key.Id, &key.Name, &key.Prefix, &key.Hash, &scopes, &expiresAt, &lastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key.Scopes = storage.SplitScopes(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return key, nil
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/storage/storagetest"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestGetMoviesSorted(t *testing.T) {
+	storagetest.MoviesSorted(t, newTestStorage(t))
+}
+
+func TestGetMovieByFragment(t *testing.T) {
+	storagetest.FragmentSearch(t, newTestStorage(t))
+}
+
+func TestDelete(t *testing.T) {
+	storagetest.Delete(t, newTestStorage(t))
+}
+
+func TestRefreshTokens(t *testing.T) {
+	storagetest.RefreshTokens(t, newTestStorage(t))
+}
+
+func TestAPIKeys(t *testing.T) {
+	storagetest.APIKeys(t, newTestStorage(t))
+}
+
+func TestSearchMovies(t *testing.T) {
+	s := newTestStorage(t)
+	if !s.fts5 {
+		t.Skip("SQLite built without FTS5, run with -tags sqlite_fts5")
+	}
+
+	storagetest.Search(t, s)
+}
+
+func TestSearchMoviesWithoutFTS5(t *testing.T) {
+	s := newTestStorage(t)
+	s.fts5 = false
+
+	storagetest.Search(t, s)
+}
@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/rmnvlv/golang-cinema-api/internal/storage/sqlite")
+
+// startSpan starts the span of one Storage method as a child of whatever
+// span ctx carries. operation is the kind of statement the method runs.
+func startSpan(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "sqlite."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", operation),
+		),
+	)
+}
+
+// endSpan records the rows the method returned or wrote, none if it failed,
+// and its error, then ends span.
+func endSpan(span trace.Span, rows int, err error) {
+	if err != nil {
+		rows = 0
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.Int("db.rows", rows))
+	span.End()
+}
+
+// affected returns how many rows result changed, for the span only: a
+// driver that can't tell makes it 0 rather than failing the call.
+func affected(result sql.Result) int {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+
+	return int(n)
+}
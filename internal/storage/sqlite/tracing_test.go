@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+var (
+	exporterOnce sync.Once
+	exporter     *tracetest.InMemoryExporter
+)
+
+// spanExporter installs a tracer provider that records every span in
+// memory. The global provider can only be set once per process, so tests
+// share the exporter and reset it.
+func spanExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporterOnce.Do(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	})
+	exporter.Reset()
+
+	return exporter
+}
+
+// span returns the only ended span called name.
+func span(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+
+	var found []tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.Name == name {
+			found = append(found, s)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("%d spans called %s, want 1", len(found), name)
+	}
+
+	return found[0]
+}
+
+func rows(t *testing.T, s tracetest.SpanStub) int64 {
+	t.Helper()
+
+	for _, kv := range s.Attributes {
+		if kv.Key == "db.rows" {
+			return kv.Value.AsInt64()
+		}
+	}
+	t.Fatalf("span %s has no db.rows", s.Name)
+	return 0
+}
+
+func TestSpans(t *testing.T) {
+	s := newTestStorage(t)
+	exporter := spanExporter(t)
+	ctx := context.Background()
+	date := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+
+	movieId, err := s.CreateMovie(ctx, "Alien", "", date, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cast []models.CastEntry
+	for _, name := range []string{"Sigourney Weaver", "Tom Skerritt"} {
+		actorId, err := s.CreateActor(ctx, name, "", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cast = append(cast, models.CastEntry{ActorId: actorId})
+	}
+	if err := s.ReplaceRules(ctx, movieId, cast); err != nil {
+		t.Fatal(err)
+	}
+
+	rating := int8(9)
+	if _, err := s.UpdateMovie(ctx, movieId, storage.MoviePatch{Rating: &rating}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateMovie(ctx, movieId, storage.MoviePatch{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRules(ctx, movieId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.TouchAPIKey(ctx, 42, date); !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		t.Fatalf("TouchAPIKey: err = %v, want %v", err, storage.ErrAPIKeyNotFound)
+	}
+	if err := s.DeliteMovie(ctx, int(movieId)+1); !errors.Is(err, storage.ErrMovieNotFound) {
+		t.Fatalf("DeliteMovie: err = %v, want %v", err, storage.ErrMovieNotFound)
+	}
+
+	insert := span(t, exporter, "sqlite.CreateMovie")
+	if got := rows(t, insert); got != 1 {
+		t.Errorf("CreateMovie: db.rows = %d, want 1", got)
+	}
+	if !hasAttr(insert, attribute.String("db.operation", "insert")) || !hasAttr(insert, attribute.String("db.system", "sqlite")) {
+		t.Errorf("CreateMovie: attributes = %v", insert.Attributes)
+	}
+
+	if got := rows(t, span(t, exporter, "sqlite.ReplaceRules")); got != 2 {
+		t.Errorf("ReplaceRules: db.rows = %d, want 2", got)
+	}
+	if got := rows(t, span(t, exporter, "sqlite.DeleteRules")); got != 2 {
+		t.Errorf("DeleteRules: db.rows = %d, want 2", got)
+	}
+	if got := rows(t, span(t, exporter, "sqlite.TouchAPIKey")); got != 0 {
+		t.Errorf("TouchAPIKey of a missing key: db.rows = %d, want 0", got)
+	}
+
+	var updates []int64
+	for _, s := range exporter.GetSpans() {
+		if s.Name == "sqlite.UpdateMovie" {
+			updates = append(updates, rows(t, s))
+		}
+	}
+	if len(updates) != 2 || updates[0] != 1 || updates[1] != 0 {
+		t.Errorf("UpdateMovie: db.rows = %v, want [1 0] for a change and an empty patch", updates)
+	}
+
+	failed := span(t, exporter, "sqlite.DeliteMovie")
+	if got := rows(t, failed); got != 0 {
+		t.Errorf("DeliteMovie of a missing movie: db.rows = %d, want 0", got)
+	}
+	if failed.Status.Code != codes.Error || len(failed.Events) == 0 {
+		t.Errorf("DeliteMovie of a missing movie: status %v, %d events, want an error", failed.Status, len(failed.Events))
+	}
+}
+
+func TestSpanParent(t *testing.T) {
+	s := newTestStorage(t)
+	exporter := spanExporter(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	movieId, err := s.CreateMovie(ctx, "Alien", "", time.Now(), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rating := int8(9)
+	if _, err := s.UpdateMovie(ctx, movieId, storage.MoviePatch{Rating: &rating}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	update := span(t, exporter, "sqlite.UpdateMovie")
+	if update.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("UpdateMovie is not a child of the request span")
+	}
+	if get := span(t, exporter, "sqlite.GetMovie"); get.Parent.SpanID() != update.SpanContext.SpanID() {
+		t.Errorf("GetMovie is not a child of UpdateMovie")
+	}
+}
+
+func hasAttr(s tracetest.SpanStub, want attribute.KeyValue) bool {
+	for _, kv := range s.Attributes {
+		if kv == want {
+			return true
+		}
+	}
+
+	return false
+}
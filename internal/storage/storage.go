@@ -1,8 +1,58 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
 
 var (
-	ErrActorExists = errors.New("actor exists")
-	ErrFilmExists  = errors.New("film exists")
+	ErrActorExists    = errors.New("actor exists")
+	ErrFilmExists     = errors.New("film exists")
+	ErrMovieNotFound  = errors.New("movie not found")
+	ErrActorNotFound  = errors.New("actor not found")
+	ErrRuleExists     = errors.New("actor already linked to movie")
+	ErrUserExists     = errors.New("user exists")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrAPIKeyExists   = errors.New("api key exists")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
 )
+
+// Repository is the full set of movie, actor, cast, account, refresh token
+// and API key operations a storage backend has to provide. Handlers depend on narrow
+// subsets of it.
+type Repository interface {
+	CreateMovie(ctx context.Context, title string, description string, date time.Time, rating int8) (int64, error)
+	UpdateMovie(ctx context.Context, filmId int64, patch MoviePatch) (models.Movie, error)
+	DeliteMovie(ctx context.Context, filmId int) error
+	GetMovie(ctx context.Context, filmId int64) (models.Movie, error)
+	GetMoviesSorted(ctx context.Context, filter MovieFilter, sortBy string, page Page) ([]models.Movie, string, error)
+	GetMovieByFragment(ctx context.Context, fragmentType string, fragment string, fuzzy bool, limit int) ([]models.SearchResult, error)
+	SearchMovies(ctx context.Context, q string, limit int) ([]models.SearchResult, error)
+
+	CreateActor(ctx context.Context, name string, gender string, birth time.Time) (int64, error)
+	UpdateActor(ctx context.Context, actorId int64, patch ActorPatch) (models.Actor, error)
+	DeleteActor(ctx context.Context, actorId int64) error
+	GetActor(ctx context.Context, actorId int64) (models.Actor, error)
+	GetActors(ctx context.Context, page Page) ([]models.Actor, string, error)
+
+	CreateRule(ctx context.Context, movieId int, cast []models.CastEntry) error
+	ReplaceRules(ctx context.Context, movieId int64, cast []models.CastEntry) error
+	DeleteRules(ctx context.Context, movieId int64) error
+
+	CreateUser(ctx context.Context, username string, passwordHash string, role models.UserRole) (int64, error)
+	GetUser(ctx context.Context, username string) (models.User, error)
+
+	CreateRefreshToken(ctx context.Context, tokenId string, userId int64, expiresAt time.Time) error
+	UseRefreshToken(ctx context.Context, tokenId string) error
+
+	CreateAPIKey(ctx context.Context, key models.APIKey) (int64, error)
+	GetAPIKey(ctx context.Context, prefix string) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, keyId int64) error
+	TouchAPIKey(ctx context.Context, keyId int64, usedAt time.Time) error
+}
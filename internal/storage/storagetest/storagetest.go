@@ -0,0 +1,516 @@
+// Package storagetest holds behaviour tests shared by every
+// storage.Repository backend.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/storage"
+)
+
+// movies is the fixture MoviesSorted loads. Ratings and dates repeat so
+// that secondary keys and the id tie-breaker decide part of every order.
+var movies = []struct {
+	title  string
+	date   string
+	rating int8
+}{
+	{"Alien", "1979-05-25", 8},
+	{"Brazil", "1985-02-20", 8},
+	{"Casablanca", "1942-11-26", 9},
+	{"Dune", "1985-02-20", 6},
+	{"Eraserhead", "1977-03-19", 8},
+}
+
+// MoviesSorted checks GetMoviesSorted on an empty repository: the order of
+// every sort, and that walking the pages by cursor gives the same listing.
+func MoviesSorted(t *testing.T, repo storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		date, err := time.Parse(time.DateOnly, movie.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i], err = repo.CreateMovie(ctx, movie.title, "", date, movie.rating)
+		if err != nil {
+			t.Fatalf("CreateMovie(%q): %v", movie.title, err)
+		}
+	}
+
+	// want lists fixture positions, 1-based, in the expected order.
+	tests := []struct {
+		sort string
+		want []int
+	}{
+		{"", []int{3, 1, 2, 5, 4}},
+		{"rating:asc", []int{4, 1, 2, 5, 3}},
+		{"title", []int{1, 2, 3, 4, 5}},
+		{"title:desc", []int{5, 4, 3, 2, 1}},
+		{"date", []int{3, 5, 1, 2, 4}},
+		{"date:desc", []int{2, 4, 1, 5, 3}},
+		{"rating,date", []int{3, 5, 1, 2, 4}},
+		{"rating:asc,date:desc", []int{4, 2, 1, 5, 3}},
+		{"date:desc,title:desc", []int{4, 2, 1, 5, 3}},
+	}
+
+	for _, tt := range tests {
+		want := make([]int64, len(tt.want))
+		for i, n := range tt.want {
+			want[i] = ids[n-1]
+		}
+
+		t.Run("sort="+tt.sort, func(t *testing.T) {
+			got, next, err := repo.GetMoviesSorted(ctx, storage.MovieFilter{}, tt.sort, storage.Page{})
+			if err != nil {
+				t.Fatalf("GetMoviesSorted: %v", err)
+			}
+			if next != "" {
+				t.Errorf("next = %q, want none on the last page", next)
+			}
+			if got := movieIds(got); !slices.Equal(got, want) {
+				t.Errorf("ids = %v, want %v", got, want)
+			}
+
+			for limit := 1; limit <= len(movies); limit++ {
+				if got := walk(t, repo, storage.MovieFilter{}, tt.sort, limit); !slices.Equal(got, want) {
+					t.Errorf("limit %d: ids = %v, want %v", limit, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("filter and cursor", func(t *testing.T) {
+		actorId, err := repo.CreateActor(ctx, "Sigourney Weaver", "female", time.Date(1949, 10, 8, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("CreateActor: %v", err)
+		}
+		for _, movieId := range []int64{ids[0], ids[3]} {
+			if err := repo.CreateRule(ctx, int(movieId), []models.CastEntry{{ActorId: actorId}}); err != nil {
+				t.Fatalf("CreateRule(%d): %v", movieId, err)
+			}
+		}
+
+		yearFrom, yearTo, ratingMin, ratingMax := 1970, 1990, 6, 8
+		filter := storage.MovieFilter{
+			Title:     "e",
+			Actor:     "weaver",
+			YearFrom:  &yearFrom,
+			YearTo:    &yearTo,
+			RatingMin: &ratingMin,
+			RatingMax: &ratingMax,
+		}
+
+		want := []int64{ids[3], ids[0]}
+		if got := walk(t, repo, filter, "rating:asc,title", 1); !slices.Equal(got, want) {
+			t.Errorf("ids = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("cursor from another sort", func(t *testing.T) {
+		_, next, err := repo.GetMoviesSorted(ctx, storage.MovieFilter{}, "title", storage.Page{Limit: 1})
+		if err != nil {
+			t.Fatalf("GetMoviesSorted: %v", err)
+		}
+
+		_, _, err = repo.GetMoviesSorted(ctx, storage.MovieFilter{}, "date", storage.Page{Limit: 1, Cursor: next})
+		if !errors.Is(err, storage.ErrInvalidCursor) {
+			t.Errorf("err = %v, want %v", err, storage.ErrInvalidCursor)
+		}
+	})
+}
+
+// walk follows next cursors from the first page to the last and returns
+// the ids it saw.
+func walk(t *testing.T, repo storage.Repository, filter storage.MovieFilter, sortBy string, limit int) []int64 {
+	t.Helper()
+
+	var ids []int64
+	page := storage.Page{Limit: limit}
+	for range len(movies) + 1 {
+		got, next, err := repo.GetMoviesSorted(context.Background(), filter, sortBy, page)
+		if err != nil {
+			t.Fatalf("GetMoviesSorted(cursor %q): %v", page.Cursor, err)
+		}
+		if len(got) > limit {
+			t.Fatalf("page has %d movies, limit is %d", len(got), limit)
+		}
+		ids = append(ids, movieIds(got)...)
+
+		if next == "" {
+			return ids
+		}
+		page.Cursor = next
+	}
+
+	t.Fatalf("limit %d: cursor never reached the last page", limit)
+	return nil
+}
+
+func movieIds(movies []models.Movie) []int64 {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.Id
+	}
+
+	return ids
+}
+
+// FragmentSearch checks GetMovieByFragment on an empty repository, in exact
+// and fuzzy mode, for titles and cast names.
+func FragmentSearch(t *testing.T, repo storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+	date := time.Date(1986, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	titles := []string{"Кин-дза-дза!", "Alien: Covenant", "Spider-Man", "The Matrix"}
+	ids := make(map[string]int64)
+	for _, title := range titles {
+		id, err := repo.CreateMovie(ctx, title, "", date, 8)
+		if err != nil {
+			t.Fatalf("CreateMovie(%q): %v", title, err)
+		}
+		ids[title] = id
+	}
+
+	actorId, err := repo.CreateActor(ctx, "Сигурни Уивер", "female", date)
+	if err != nil {
+		t.Fatalf("CreateActor: %v", err)
+	}
+	if err := repo.CreateRule(ctx, int(ids["Alien: Covenant"]), []models.CastEntry{{ActorId: actorId}}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	tests := []struct {
+		fragmentType string
+		fragment     string
+		fuzzy        bool
+		want         []string
+	}{
+		{"title", "matrix", false, []string{"The Matrix"}},
+		{"title", "atri", false, []string{"The Matrix"}},
+		{"title", "дза", false, []string{"Кин-дза-дза!"}},
+		{"title", "matrx", false, nil},
+		{"title", "kin dza", true, []string{"Кин-дза-дза!"}},
+		{"title", "matrx", true, []string{"The Matrix"}},
+		{"title", "man", true, []string{"Spider-Man"}},
+		{"title", "Алиен", true, []string{"Alien: Covenant"}},
+		{"title", "kovenant", true, nil},
+		{"title", "Hlien", true, nil},
+		{"title", "atrix", true, nil},
+		{"title", "", true, nil},
+		{"actor", "Уивер", false, []string{"Alien: Covenant"}},
+		{"actor", "sigorni", true, []string{"Alien: Covenant"}},
+		{"actor", "weaver", true, nil},
+	}
+
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s=%q fuzzy=%v", tt.fragmentType, tt.fragment, tt.fuzzy)
+		t.Run(name, func(t *testing.T) {
+			results, err := repo.GetMovieByFragment(ctx, tt.fragmentType, tt.fragment, tt.fuzzy, storage.DefaultLimit)
+			if err != nil {
+				t.Fatalf("GetMovieByFragment: %v", err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Movie.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		results, err := repo.GetMovieByFragment(ctx, "title", "a", false, 2)
+		if err != nil {
+			t.Fatalf("GetMovieByFragment: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("%d results, want 2", len(results))
+		}
+	})
+
+	t.Run("ranked before limit", func(t *testing.T) {
+		date := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+		for i := range 30 {
+			if _, err := repo.CreateMovie(ctx, fmt.Sprintf("Nostromi %d", i), "", date, 5); err != nil {
+				t.Fatalf("CreateMovie: %v", err)
+			}
+		}
+		if _, err := repo.CreateMovie(ctx, "Nostromo", "", date, 5); err != nil {
+			t.Fatalf("CreateMovie: %v", err)
+		}
+
+		results, err := repo.GetMovieByFragment(ctx, "title", "nostromo", true, 1)
+		if err != nil {
+			t.Fatalf("GetMovieByFragment: %v", err)
+		}
+		if len(results) != 1 || results[0].Movie.Title != "Nostromo" {
+			t.Errorf("results = %+v, want the exact match, added last", results)
+		}
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := repo.GetMovieByFragment(ctx, "plot", "alien", false, storage.DefaultLimit)
+		if !errors.Is(err, storage.ErrInvalidFilter) {
+			t.Errorf("err = %v, want %v", err, storage.ErrInvalidFilter)
+		}
+	})
+}
+
+// Delete checks that DeleteActor, DeliteMovie and DeleteRules remove what
+// exists and report ErrActorNotFound and ErrMovieNotFound for what doesn't.
+func Delete(t *testing.T, repo storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+	date := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+
+	movieId, err := repo.CreateMovie(ctx, "Alien", "", date, 8)
+	if err != nil {
+		t.Fatalf("CreateMovie: %v", err)
+	}
+	actorId, err := repo.CreateActor(ctx, "Sigourney Weaver", "female", date)
+	if err != nil {
+		t.Fatalf("CreateActor: %v", err)
+	}
+
+	if err := repo.ReplaceRules(ctx, movieId, []models.CastEntry{{ActorId: actorId}}); err != nil {
+		t.Fatalf("ReplaceRules: %v", err)
+	}
+	if err := repo.DeleteRules(ctx, movieId); err != nil {
+		t.Errorf("DeleteRules: %v", err)
+	}
+	if err := repo.DeleteRules(ctx, movieId); err != nil {
+		t.Errorf("DeleteRules of an empty cast: %v", err)
+	}
+	if err := repo.DeleteRules(ctx, movieId+1); !errors.Is(err, storage.ErrMovieNotFound) {
+		t.Errorf("DeleteRules of a missing movie: err = %v, want %v", err, storage.ErrMovieNotFound)
+	}
+
+	if err := repo.DeleteActor(ctx, actorId); err != nil {
+		t.Errorf("DeleteActor: %v", err)
+	}
+	if err := repo.DeleteActor(ctx, actorId); !errors.Is(err, storage.ErrActorNotFound) {
+		t.Errorf("DeleteActor again: err = %v, want %v", err, storage.ErrActorNotFound)
+	}
+
+	if err := repo.DeliteMovie(ctx, int(movieId)); err != nil {
+		t.Errorf("DeliteMovie: %v", err)
+	}
+	if err := repo.DeliteMovie(ctx, int(movieId)); !errors.Is(err, storage.ErrMovieNotFound) {
+		t.Errorf("DeliteMovie again: err = %v, want %v", err, storage.ErrMovieNotFound)
+	}
+}
+
+// RefreshTokens checks that a recorded refresh token can be used exactly
+// once, and that unknown and expired ones report ErrRefreshTokenNotFound.
+func RefreshTokens(t *testing.T, repo storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+
+	userId, err := repo.CreateUser(ctx, "alice", "hash", models.RoleUser)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	now := time.Now()
+	if err := repo.CreateRefreshToken(ctx, "live", userId, now.Add(time.Hour)); err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	if err := repo.CreateRefreshToken(ctx, "expired", userId, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateRefreshToken expired: %v", err)
+	}
+
+	if err := repo.UseRefreshToken(ctx, "live"); err != nil {
+		t.Errorf("UseRefreshToken: %v", err)
+	}
+
+	for _, id := range []string{"live", "expired", "unknown"} {
+		if err := repo.UseRefreshToken(ctx, id); !errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			t.Errorf("UseRefreshToken(%q): err = %v, want %v", id, err, storage.ErrRefreshTokenNotFound)
+		}
+	}
+}
+
+// APIKeys checks the API key lifecycle: create, look up by prefix, list,
+// record a use and revoke. Unknown keys report ErrAPIKeyNotFound throughout.
+func APIKeys(t *testing.T, repo storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	key := models.APIKey{
+		Name:      "importer",
+		Prefix:    "0123456789ab",
+		Hash:      "hash",
+		Scopes:    []models.Scope{models.ScopeMoviesRead, models.ScopeMoviesWrite},
+		ExpiresAt: &expiresAt,
+	}
+	keyId, err := repo.CreateAPIKey(ctx, key)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if _, err := repo.CreateAPIKey(ctx, key); !errors.Is(err, storage.ErrAPIKeyExists) {
+		t.Errorf("CreateAPIKey with a taken prefix: err = %v, want %v", err, storage.ErrAPIKeyExists)
+	}
+
+	got, err := repo.GetAPIKey(ctx, key.Prefix)
+	if err != nil {
+		t.Fatalf("GetAPIKey: %v", err)
+	}
+	if got.Id != keyId || got.Name != key.Name || got.Hash != key.Hash || !slices.Equal(got.Scopes, key.Scopes) {
+		t.Errorf("GetAPIKey = %+v", got)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) || got.LastUsedAt != nil {
+		t.Errorf("GetAPIKey: expires at %v, last used at %v", got.ExpiresAt, got.LastUsedAt)
+	}
+	if _, err := repo.GetAPIKey(ctx, "unknown"); !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		t.Errorf("GetAPIKey unknown: err = %v, want %v", err, storage.ErrAPIKeyNotFound)
+	}
+
+	usedAt := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := repo.TouchAPIKey(ctx, keyId, usedAt); err != nil {
+		t.Errorf("TouchAPIKey: %v", err)
+	}
+	if err := repo.TouchAPIKey(ctx, keyId+1, usedAt); !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		t.Errorf("TouchAPIKey unknown: err = %v, want %v", err, storage.ErrAPIKeyNotFound)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Id != keyId {
+		t.Fatalf("ListAPIKeys = %+v", keys)
+	}
+	if keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(usedAt) {
+		t.Errorf("last used at %v, want %v", keys[0].LastUsedAt, usedAt)
+	}
+
+	if err := repo.DeleteAPIKey(ctx, keyId); err != nil {
+		t.Errorf("DeleteAPIKey: %v", err)
+	}
+	if err := repo.DeleteAPIKey(ctx, keyId); !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		t.Errorf("DeleteAPIKey again: err = %v, want %v", err, storage.ErrAPIKeyNotFound)
+	}
+	if _, err := repo.GetAPIKey(ctx, key.Prefix); !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		t.Errorf("GetAPIKey after delete: err = %v, want %v", err, storage.ErrAPIKeyNotFound)
+	}
+	if err := repo.TouchAPIKey(ctx, keyId, usedAt); !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		t.Errorf("TouchAPIKey after delete: err = %v, want %v", err, storage.ErrAPIKeyNotFound)
+	}
+}
+
+// Search checks SearchMovies on an empty repository: every term has to
+// match a title, a cast name or the description, title hits rank first, and
+// snippets are escaped HTML with only the hits marked up.
+func Search(t *testing.T, repo storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+	date := time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC)
+
+	fixture := []struct {
+		title       string
+		description string
+		cast        string
+	}{
+		{"Alien", "The crew of the Nostromo meets an <em>unknown</em> lifeform", "Sigourney Weaver"},
+		{"Solaris", "A psychologist studies an alien ocean", ""},
+		{"<script>alert(1)</script> Night", "Markup & more", ""},
+		{"Dune", "Spice & sandworms", "Kyle MacLachlan"},
+	}
+	for _, movie := range fixture {
+		movieId, err := repo.CreateMovie(ctx, movie.title, movie.description, date, 7)
+		if err != nil {
+			t.Fatalf("CreateMovie(%q): %v", movie.title, err)
+		}
+		if movie.cast == "" {
+			continue
+		}
+		actorId, err := repo.CreateActor(ctx, movie.cast, "female", date)
+		if err != nil {
+			t.Fatalf("CreateActor(%q): %v", movie.cast, err)
+		}
+		if err := repo.ReplaceRules(ctx, movieId, []models.CastEntry{{ActorId: actorId}}); err != nil {
+			t.Fatalf("ReplaceRules: %v", err)
+		}
+	}
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"alien", []string{"Alien", "Solaris"}},
+		{"ALI", []string{"Alien", "Solaris"}},
+		{"weaver", []string{"Alien"}},
+		{"nostromo crew", []string{"Alien"}},
+		{"alien weaver", []string{"Alien"}},
+		{"alien spice", nil},
+		{"script", []string{"<script>alert(1)</script> Night"}},
+		{"!!", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			results, err := repo.SearchMovies(ctx, tt.q, storage.DefaultLimit)
+			if err != nil {
+				t.Fatalf("SearchMovies: %v", err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Movie.Title)
+				if !strings.Contains(result.Snippet, "<mark>") {
+					t.Errorf("%s: snippet %q has no hit marked", result.Movie.Title, result.Snippet)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("escaped snippets", func(t *testing.T) {
+		for _, q := range []string{"script", "unknown", "spice"} {
+			results, err := repo.SearchMovies(ctx, q, storage.DefaultLimit)
+			if err != nil {
+				t.Fatalf("SearchMovies(%q): %v", q, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("SearchMovies(%q): %d results, want 1", q, len(results))
+			}
+
+			// Take out the tags the backend adds; what is left must not
+			// hold any markup from the stored text.
+			snippet := results[0].Snippet
+			rest := strings.NewReplacer("<mark>", "", "</mark>", "").Replace(snippet)
+			if strings.ContainsAny(rest, "<>") || strings.Contains(rest, "& ") {
+				t.Errorf("SearchMovies(%q): snippet %q is not escaped", q, snippet)
+			}
+			if !strings.Contains(strings.ToLower(snippet), "<mark>"+q) {
+				t.Errorf("SearchMovies(%q): snippet %q does not mark the hit", q, snippet)
+			}
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		results, err := repo.SearchMovies(ctx, "alien", 1)
+		if err != nil {
+			t.Fatalf("SearchMovies: %v", err)
+		}
+		if len(results) != 1 || results[0].Movie.Title != "Alien" {
+			t.Errorf("results = %+v, want only the title hit", results)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+// Package token issues and verifies the JWTs the API hands out on login.
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/config"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+
+	// MinSecretLen is the shortest HS256 secret accepted, in bytes.
+	MinSecretLen = 32
+
+	typeAccess  = "access"
+	typeRefresh = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are carried by both token types. Subject is the username.
+type Claims struct {
+	jwt.RegisteredClaims
+	UserId int64           `json:"uid"`
+	Role   models.UserRole `json:"role"`
+	Type   string          `json:"typ"`
+}
+
+// Pair is the body of a successful login or refresh. RefreshId and
+// RefreshExpiresAt describe the refresh token for the caller to record, so
+// that it can be used only once.
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+
+	RefreshId        string    `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
+}
+
+type key struct {
+	method jwt.SigningMethod
+	sign   interface{} // nil for keys kept only to verify
+	verify interface{}
+}
+
+// Manager signs tokens with the current key and verifies them with any
+// configured one.
+type Manager struct {
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	signingId  string
+	keys       map[string]key
+}
+
+func New(cfg config.JWT) (*Manager, error) {
+	m := &Manager{
+		issuer:     cfg.Issuer,
+		accessTTL:  cfg.AccessTTL,
+		refreshTTL: cfg.RefreshTTL,
+		signingId:  cfg.SigningKey,
+		keys:       make(map[string]key, len(cfg.Keys)),
+	}
+
+	for _, k := range cfg.Keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("%s, %w", "token.New", errors.New("key without id"))
+		}
+		if _, ok := m.keys[k.ID]; ok {
+			return nil, fmt.Errorf("%s %q, %w", "token.New", k.ID, errors.New("duplicate key id"))
+		}
+
+		parsed, err := parseKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q, %w", "token.New", k.ID, err)
+		}
+		m.keys[k.ID] = parsed
+	}
+
+	if m.signingId == "" && len(cfg.Keys) == 1 {
+		m.signingId = cfg.Keys[0].ID
+	}
+	signing, ok := m.keys[m.signingId]
+	if !ok {
+		return nil, fmt.Errorf("%s, %w", "token.New", fmt.Errorf("signing key %q is not configured", m.signingId))
+	}
+	if signing.sign == nil {
+		return nil, fmt.Errorf("%s, %w", "token.New", fmt.Errorf("signing key %q has no private part", m.signingId))
+	}
+
+	return m, nil
+}
+
+func parseKey(k config.JWTKey) (key, error) {
+	switch k.Alg {
+	case AlgHS256:
+		if len(k.Secret) < MinSecretLen {
+			return key{}, fmt.Errorf("HS256 secret must be at least %d bytes", MinSecretLen)
+		}
+		secret := []byte(k.Secret)
+		return key{method: jwt.SigningMethodHS256, sign: secret, verify: secret}, nil
+
+	case AlgEdDSA:
+		if k.PrivateKey != "" {
+			raw, err := base64.StdEncoding.DecodeString(k.PrivateKey)
+			if err != nil {
+				return key{}, fmt.Errorf("private_key: %w", err)
+			}
+
+			var private ed25519.PrivateKey
+			switch len(raw) {
+			case ed25519.SeedSize:
+				private = ed25519.NewKeyFromSeed(raw)
+			case ed25519.PrivateKeySize:
+				private = ed25519.PrivateKey(raw)
+			default:
+				return key{}, fmt.Errorf("private_key must be %d or %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize)
+			}
+			return key{method: jwt.SigningMethodEdDSA, sign: private, verify: private.Public()}, nil
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(k.PublicKey)
+		if err != nil {
+			return key{}, fmt.Errorf("public_key: %w", err)
+		}
+		if len(raw) != ed25519.PublicKeySize {
+			return key{}, fmt.Errorf("public_key must be %d bytes", ed25519.PublicKeySize)
+		}
+		return key{method: jwt.SigningMethodEdDSA, verify: ed25519.PublicKey(raw)}, nil
+	}
+
+	return key{}, fmt.Errorf("unsupported alg %q, want %s or %s", k.Alg, AlgHS256, AlgEdDSA)
+}
+
+// Issue returns a fresh access and refresh token for user.
+func (m *Manager) Issue(user models.User) (Pair, error) {
+	now := time.Now()
+
+	access, _, err := m.sign(user, typeAccess, now, m.accessTTL)
+	if err != nil {
+		return Pair{}, fmt.Errorf("%s, %w", "token.Issue.Access", err)
+	}
+
+	refresh, refreshId, err := m.sign(user, typeRefresh, now, m.refreshTTL)
+	if err != nil {
+		return Pair{}, fmt.Errorf("%s, %w", "token.Issue.Refresh", err)
+	}
+
+	return Pair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(m.accessTTL.Seconds()),
+		RefreshId:        refreshId,
+		RefreshExpiresAt: now.Add(m.refreshTTL),
+	}, nil
+}
+
+// sign returns a token of type typ for user and its random id.
+func (m *Manager) sign(user models.User, typ string, now time.Time, ttl time.Duration) (string, string, error) {
+	k := m.keys[m.signingId]
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   user.Username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        base64.RawURLEncoding.EncodeToString(id),
+		},
+		UserId: user.Id,
+		Role:   user.Role,
+		Type:   typ,
+	}
+
+	t := jwt.NewWithClaims(k.method, claims)
+	t.Header["kid"] = m.signingId
+
+	signed, err := t.SignedString(k.sign)
+	if err != nil {
+		return "", "", err
+	}
+
+	return signed, claims.ID, nil
+}
+
+// ParseAccess verifies an access token and returns its claims.
+func (m *Manager) ParseAccess(s string) (Claims, error) {
+	return m.parse(s, typeAccess)
+}
+
+// ParseRefresh verifies a refresh token and returns its claims.
+func (m *Manager) ParseRefresh(s string) (Claims, error) {
+	return m.parse(s, typeRefresh)
+}
+
+func (m *Manager) parse(s string, typ string) (Claims, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(s, &claims, m.keyFor,
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{AlgHS256, AlgEdDSA}),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%s, %w: %w", "token.Parse", ErrInvalidToken, err)
+	}
+
+	if claims.Type != typ {
+		return Claims{}, fmt.Errorf("%s, %w: want a %s token", "token.Parse", ErrInvalidToken, typ)
+	}
+
+	return claims, nil
+}
+
+// keyFor picks the verification key named by the token's kid header and
+// makes sure the token was signed with that key's algorithm, so that an
+// Ed25519 public key can never be used as an HMAC secret.
+func (m *Manager) keyFor(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	k, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("key %q is not for %s", kid, t.Method.Alg())
+	}
+
+	return k.verify, nil
+}
@@ -0,0 +1,309 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/config"
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+)
+
+var user = models.User{Id: 7, Username: "alice", Role: models.RoleAdmin}
+
+func hsKey(id string) config.JWTKey {
+	return config.JWTKey{ID: id, Alg: AlgHS256, Secret: strings.Repeat(id, MinSecretLen)}
+}
+
+// edKey returns an EdDSA key with its private part and the same key with
+// only its public part.
+func edKey(t *testing.T, id string) (config.JWTKey, config.JWTKey) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config.JWTKey{ID: id, Alg: AlgEdDSA, PrivateKey: base64.StdEncoding.EncodeToString(private.Seed())},
+		config.JWTKey{ID: id, Alg: AlgEdDSA, PublicKey: base64.StdEncoding.EncodeToString(public)}
+}
+
+func newManager(t *testing.T, signing string, keys ...config.JWTKey) *Manager {
+	t.Helper()
+
+	m, err := New(config.JWT{
+		Issuer:     "test",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+		SigningKey: signing,
+		Keys:       keys,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return m
+}
+
+// forge signs claims with method and secret under kid, bypassing Manager.
+func forge(t *testing.T, method jwt.SigningMethod, kid string, secret interface{}) string {
+	t.Helper()
+
+	now := time.Now()
+	token := jwt.NewWithClaims(method, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test",
+			Subject:   user.Username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		UserId: user.Id,
+		Role:   models.RoleAdmin,
+		Type:   typeAccess,
+	})
+	token.Header["kid"] = kid
+
+	s, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func wantInvalid(t *testing.T, name string, err error) {
+	t.Helper()
+
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("%s: err = %v, want %v", name, err, ErrInvalidToken)
+	}
+}
+
+func TestIssueAndParse(t *testing.T) {
+	private, _ := edKey(t, "ed")
+
+	for _, key := range []config.JWTKey{hsKey("hs"), private} {
+		t.Run(key.Alg, func(t *testing.T) {
+			m := newManager(t, "", key)
+
+			pair, err := m.Issue(user)
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+			if pair.TokenType != "Bearer" || pair.ExpiresIn != 60 {
+				t.Errorf("pair = %+v", pair)
+			}
+
+			access, err := m.ParseAccess(pair.AccessToken)
+			if err != nil {
+				t.Fatalf("ParseAccess: %v", err)
+			}
+			if access.Subject != user.Username || access.UserId != user.Id || access.Role != user.Role || access.Issuer != "test" {
+				t.Errorf("access claims = %+v", access)
+			}
+
+			refresh, err := m.ParseRefresh(pair.RefreshToken)
+			if err != nil {
+				t.Fatalf("ParseRefresh: %v", err)
+			}
+			if refresh.ID != pair.RefreshId || refresh.ID == access.ID {
+				t.Errorf("refresh id = %q, pair says %q, access id %q", refresh.ID, pair.RefreshId, access.ID)
+			}
+			if got := refresh.ExpiresAt.Time; got.Sub(pair.RefreshExpiresAt).Abs() > time.Second {
+				t.Errorf("refresh expires at %v, pair says %v", got, pair.RefreshExpiresAt)
+			}
+
+			again, err := m.Issue(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.RefreshId == pair.RefreshId {
+				t.Errorf("two pairs share refresh id %q", pair.RefreshId)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	m, err := New(config.JWT{
+		Issuer:     "test",
+		AccessTTL:  -time.Minute,
+		RefreshTTL: -time.Minute,
+		Keys:       []config.JWTKey{hsKey("hs")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair, err := m.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.ParseAccess(pair.AccessToken)
+	wantInvalid(t, "expired access token", err)
+	if !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("err = %v, want %v", err, jwt.ErrTokenExpired)
+	}
+	_, err = m.ParseRefresh(pair.RefreshToken)
+	wantInvalid(t, "expired refresh token", err)
+}
+
+func TestTypeMismatch(t *testing.T) {
+	m := newManager(t, "", hsKey("hs"))
+
+	pair, err := m.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.ParseAccess(pair.RefreshToken)
+	wantInvalid(t, "refresh token as access token", err)
+	_, err = m.ParseRefresh(pair.AccessToken)
+	wantInvalid(t, "access token as refresh token", err)
+}
+
+func TestUnknownKey(t *testing.T) {
+	a := newManager(t, "", hsKey("a"))
+
+	// Same secret, different id: the kid must name a configured key.
+	renamed := hsKey("a")
+	renamed.ID = "b"
+	b := newManager(t, "", renamed)
+
+	pair, err := a.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = b.ParseAccess(pair.AccessToken)
+	wantInvalid(t, "unknown kid", err)
+
+	_, err = a.ParseAccess(forge(t, jwt.SigningMethodHS256, "", []byte(hsKey("a").Secret)))
+	wantInvalid(t, "no kid", err)
+}
+
+func TestIssuerMismatch(t *testing.T) {
+	m := newManager(t, "", hsKey("hs"))
+	other, err := New(config.JWT{Issuer: "other", AccessTTL: time.Minute, Keys: []config.JWTKey{hsKey("hs")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair, err := other.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.ParseAccess(pair.AccessToken)
+	wantInvalid(t, "foreign issuer", err)
+}
+
+func TestRotation(t *testing.T) {
+	oldPrivate, oldPublic := edKey(t, "2024")
+	old := newManager(t, "", oldPrivate)
+
+	oldPair, err := old.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key signs; the old one is kept, public part only, to verify.
+	rotated := newManager(t, "2025", oldPublic, hsKey("2025"))
+
+	if _, err := rotated.ParseAccess(oldPair.AccessToken); err != nil {
+		t.Errorf("token signed with the retired key: %v", err)
+	}
+
+	newPair, err := rotated.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(newPair.AccessToken, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != "2025" {
+		t.Errorf("kid = %v, want 2025", kid)
+	}
+	if parsed.Method.Alg() != AlgHS256 {
+		t.Errorf("alg = %s, want %s", parsed.Method.Alg(), AlgHS256)
+	}
+
+	_, err = old.ParseAccess(newPair.AccessToken)
+	wantInvalid(t, "new key on a binary that predates it", err)
+
+	// Once the retired key is dropped, its tokens stop working.
+	dropped := newManager(t, "2025", hsKey("2025"))
+	_, err = dropped.ParseAccess(oldPair.AccessToken)
+	wantInvalid(t, "retired key removed", err)
+}
+
+func TestAlgConfusion(t *testing.T) {
+	private, public := edKey(t, "ed")
+	m := newManager(t, "ed", private, hsKey("hs"))
+
+	raw, err := base64.StdEncoding.DecodeString(public.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Ed25519 public key is no secret: it must not verify an HMAC. The
+	// key's own alg decides, not whatever the library makes of its type.
+	_, err = m.ParseAccess(forge(t, jwt.SigningMethodHS256, "ed", raw))
+	wantInvalid(t, "HS256 with the EdDSA public key", err)
+	if err == nil || !strings.Contains(err.Error(), `key "ed" is not for HS256`) {
+		t.Errorf("HS256 with the EdDSA public key: err = %v, want the key's alg enforced", err)
+	}
+
+	// An EdDSA signature can't claim the HS256 key's id.
+	_, attacker, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.ParseAccess(forge(t, jwt.SigningMethodEdDSA, "hs", attacker))
+	wantInvalid(t, "EdDSA under the HS256 key id", err)
+
+	_, err = m.ParseAccess(forge(t, jwt.SigningMethodNone, "ed", jwt.UnsafeAllowNoneSignatureType))
+	wantInvalid(t, "alg none", err)
+
+	pair, err := m.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(pair.AccessToken, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"test","sub":"mallory","uid":1,"role":"admin","typ":"access","exp":9999999999}`))
+	_, err = m.ParseAccess(strings.Join(parts, "."))
+	wantInvalid(t, "tampered claims", err)
+}
+
+func TestNewRejectsBadKeys(t *testing.T) {
+	_, public := edKey(t, "ed")
+	short := hsKey("hs")
+	short.Secret = "too short"
+
+	tests := []struct {
+		name string
+		cfg  config.JWT
+	}{
+		{"short secret", config.JWT{Keys: []config.JWTKey{short}}},
+		{"no id", config.JWT{Keys: []config.JWTKey{{Alg: AlgHS256, Secret: hsKey("x").Secret}}}},
+		{"duplicate id", config.JWT{SigningKey: "hs", Keys: []config.JWTKey{hsKey("hs"), hsKey("hs")}}},
+		{"unknown alg", config.JWT{Keys: []config.JWTKey{{ID: "rs", Alg: "RS256"}}}},
+		{"signing key not configured", config.JWT{SigningKey: "missing", Keys: []config.JWTKey{hsKey("hs")}}},
+		{"ambiguous signing key", config.JWT{Keys: []config.JWTKey{hsKey("a"), hsKey("b")}}},
+		{"signing key without private part", config.JWT{Keys: []config.JWTKey{public}}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); err == nil {
+			t.Errorf("%s: New succeeded", tt.name)
+		}
+	}
+}
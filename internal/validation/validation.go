@@ -0,0 +1,195 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/patch"
+)
+
+const (
+	MaxTitleLen       = 150
+	MaxDescriptionLen = 1000
+	MaxNameLen        = 100
+	MaxCharacterLen   = 100
+	MaxUsernameLen    = 64
+	MinPasswordLen    = 8
+	MaxPasswordLen    = 72 // bytes; bcrypt ignores anything longer
+	MinRating         = 0
+	MaxRating         = 10
+)
+
+// Genders are the values an actor's gender may take.
+var Genders = []string{"male", "female", "other"}
+
+// FieldError is one violated rule. Field is the JSON name of the offending
+// input, so that clients can show Message next to it.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is every violation found in one payload.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Validator collects field errors. The zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// Check records message for field unless ok holds.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Field: field, Message: message})
+	}
+}
+
+// Valid reports whether no errors were recorded.
+func (v *Validator) Valid() bool {
+	return len(v.errs) == 0
+}
+
+// Errors returns the recorded errors, or nil if there are none.
+func (v *Validator) Errors() Errors {
+	return v.errs
+}
+
+func (v *Validator) Title(title string) {
+	n := utf8.RuneCountInString(title)
+	v.Check(strings.TrimSpace(title) != "", "title", "must not be blank")
+	v.Check(n <= MaxTitleLen, "title", fmt.Sprintf("must be at most %d characters", MaxTitleLen))
+}
+
+func (v *Validator) Description(description string) {
+	n := utf8.RuneCountInString(description)
+	v.Check(n <= MaxDescriptionLen, "description", fmt.Sprintf("must be at most %d characters", MaxDescriptionLen))
+}
+
+func (v *Validator) Rating(rating int) {
+	v.Check(rating >= MinRating && rating <= MaxRating, "rating",
+		fmt.Sprintf("must be between %d and %d", MinRating, MaxRating))
+}
+
+func (v *Validator) Name(name string) {
+	n := utf8.RuneCountInString(name)
+	v.Check(strings.TrimSpace(name) != "", "name", "must not be blank")
+	v.Check(n <= MaxNameLen, "name", fmt.Sprintf("must be at most %d characters", MaxNameLen))
+}
+
+func (v *Validator) Gender(gender string) {
+	ok := false
+	for _, g := range Genders {
+		if gender == g {
+			ok = true
+			break
+		}
+	}
+	v.Check(ok, "gender", "must be one of "+strings.Join(Genders, ", "))
+}
+
+func (v *Validator) Username(username string) {
+	n := utf8.RuneCountInString(username)
+	v.Check(n > 0 && !strings.ContainsFunc(username, unicode.IsSpace), "username", "must be non-empty and contain no spaces")
+	v.Check(n <= MaxUsernameLen, "username", fmt.Sprintf("must be at most %d characters", MaxUsernameLen))
+}
+
+func (v *Validator) Password(password string) {
+	v.Check(utf8.RuneCountInString(password) >= MinPasswordLen, "password",
+		fmt.Sprintf("must be at least %d characters", MinPasswordLen))
+	v.Check(len(password) <= MaxPasswordLen, "password", fmt.Sprintf("must be at most %d bytes", MaxPasswordLen))
+}
+
+func (v *Validator) Role(role models.UserRole) {
+	v.Check(role.Valid(), "role", fmt.Sprintf("must be %s or %s", models.RoleUser, models.RoleAdmin))
+}
+
+// Scopes checks that an API key asks for at least one known scope.
+func (v *Validator) Scopes(scopes []models.Scope) {
+	v.Check(len(scopes) > 0, "scopes", "must not be empty")
+	for i, scope := range scopes {
+		v.Check(scope.Valid(), fmt.Sprintf("scopes[%d]", i), fmt.Sprintf("must be one of %s, %s, %s",
+			models.ScopeMoviesRead, models.ScopeMoviesWrite, models.ScopeActorsWrite))
+	}
+}
+
+// CastRole checks the part an actor plays. prefix is put in front of each
+// field name, so that entries of a list can be told apart.
+func (v *Validator) CastRole(prefix string, role models.Role) {
+	v.Check(utf8.RuneCountInString(role.Character) <= MaxCharacterLen, prefix+"character",
+		fmt.Sprintf("must be at most %d characters", MaxCharacterLen))
+	v.Check(role.BillingOrder >= 0, prefix+"billing_order", "must not be negative")
+	v.Check(role.CreditType == "" || role.CreditType.Valid(), prefix+"credit_type",
+		fmt.Sprintf("must be one of %s, %s, %s, %s",
+			models.CreditLead, models.CreditSupporting, models.CreditCameo, models.CreditVoice))
+}
+
+// Cast checks every entry of a movie's cast. A billing order may be used
+// only once; 0, the default, means unbilled and may repeat.
+func (v *Validator) Cast(cast []models.CastEntry) {
+	billed := make(map[int]int)
+	for i, entry := range cast {
+		prefix := fmt.Sprintf("cast[%d].", i)
+		v.CastRole(prefix, entry.Role)
+
+		if entry.BillingOrder <= 0 {
+			continue
+		}
+		if first, ok := billed[entry.BillingOrder]; ok {
+			v.Check(false, prefix+"billing_order", fmt.Sprintf("duplicates cast[%d]", first))
+			continue
+		}
+		billed[entry.BillingOrder] = i
+	}
+}
+
+// Date parses a YYYY-MM-DD date, recording an error for field if it isn't one.
+func (v *Validator) Date(field, value string) (time.Time, bool) {
+	date, err := time.Parse(time.DateOnly, value)
+	v.Check(err == nil, field, "must be a date in YYYY-MM-DD format")
+
+	return date, err == nil
+}
+
+// Birth checks that an actor's birth date is a valid date not in the future.
+func (v *Validator) Birth(value string) (time.Time, bool) {
+	birth, ok := v.Date("birth", value)
+	if !ok {
+		return birth, false
+	}
+
+	ok = !birth.After(time.Now())
+	v.Check(ok, "birth", "must not be in the future")
+
+	return birth, ok
+}
+
+// Member checks one member of a merge patch. It reports whether the member
+// was given a value, in which case the caller validates that value; an
+// absent member is fine, while null or a value of the wrong type (kind, for
+// example "a string") is recorded as an error.
+func Member[T any](v *Validator, field, kind string, f patch.Field[T]) (T, bool) {
+	switch {
+	case !f.Set:
+	case f.Null:
+		v.Check(false, field, "must not be null")
+	case f.Invalid:
+		v.Check(false, field, "must be "+kind)
+	default:
+		return f.Value, true
+	}
+
+	return f.Value, false
+}
@@ -0,0 +1,157 @@
+package validation
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmnvlv/golang-cinema-api/internal/models"
+	"github.com/rmnvlv/golang-cinema-api/internal/patch"
+)
+
+// fields lists the fields v recorded errors for, in order.
+func fields(v *Validator) []string {
+	var fields []string
+	for _, fe := range v.Errors() {
+		fields = append(fields, fe.Field)
+	}
+
+	return fields
+}
+
+func TestRules(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	tests := []struct {
+		name  string
+		check func(v *Validator)
+		want  []string
+	}{
+		{"title", func(v *Validator) { v.Title("Alien") }, nil},
+		{"title at limit", func(v *Validator) { v.Title(strings.Repeat("é", MaxTitleLen)) }, nil},
+		{"blank title", func(v *Validator) { v.Title(" \t") }, []string{"title"}},
+		{"long title", func(v *Validator) { v.Title(strings.Repeat("a", MaxTitleLen+1)) }, []string{"title"}},
+		{"description", func(v *Validator) { v.Description("") }, nil},
+		{"long description", func(v *Validator) { v.Description(strings.Repeat("a", MaxDescriptionLen+1)) }, []string{"description"}},
+		{"ratings", func(v *Validator) { v.Rating(MinRating); v.Rating(MaxRating) }, nil},
+		{"low rating", func(v *Validator) { v.Rating(MinRating - 1) }, []string{"rating"}},
+		{"high rating", func(v *Validator) { v.Rating(MaxRating + 1) }, []string{"rating"}},
+		{"name", func(v *Validator) { v.Name("Sigourney Weaver") }, nil},
+		{"blank name", func(v *Validator) { v.Name("") }, []string{"name"}},
+		{"long name", func(v *Validator) { v.Name(strings.Repeat("a", MaxNameLen+1)) }, []string{"name"}},
+		{"genders", func(v *Validator) {
+			for _, g := range Genders {
+				v.Gender(g)
+			}
+		}, nil},
+		{"unknown gender", func(v *Validator) { v.Gender("Female") }, []string{"gender"}},
+		{"username", func(v *Validator) { v.Username("ripley") }, nil},
+		{"username with space", func(v *Validator) { v.Username("ellen ripley") }, []string{"username"}},
+		{"empty username", func(v *Validator) { v.Username("") }, []string{"username"}},
+		{"password", func(v *Validator) { v.Password("nostromo") }, nil},
+		{"short password", func(v *Validator) { v.Password("short") }, []string{"password"}},
+		{"long password", func(v *Validator) { v.Password(strings.Repeat("é", MaxPasswordLen/2+1)) }, []string{"password"}},
+		{"role", func(v *Validator) { v.Role(models.RoleAdmin) }, nil},
+		{"unknown role", func(v *Validator) { v.Role("root") }, []string{"role"}},
+		{"scopes", func(v *Validator) { v.Scopes([]models.Scope{models.ScopeMoviesRead}) }, nil},
+		{"no scopes", func(v *Validator) { v.Scopes(nil) }, []string{"scopes"}},
+		{"unknown scope", func(v *Validator) { v.Scopes([]models.Scope{models.ScopeMoviesRead, "movies:*"}) }, []string{"scopes[1]"}},
+		{"date", func(v *Validator) { v.Date("date", "1979-05-25") }, nil},
+		{"bad date", func(v *Validator) { v.Date("date", "25.05.1979") }, []string{"date"}},
+		{"impossible date", func(v *Validator) { v.Date("date", "1979-02-30") }, []string{"date"}},
+		{"birth", func(v *Validator) { v.Birth("1949-10-08") }, nil},
+		{"future birth", func(v *Validator) { v.Birth(tomorrow) }, []string{"birth"}},
+		{"bad birth", func(v *Validator) { v.Birth("") }, []string{"birth"}},
+		{"every error", func(v *Validator) { v.Title(""); v.Rating(11); v.Gender("") }, []string{"title", "rating", "gender"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			tt.check(&v)
+
+			if got := fields(&v); !slices.Equal(got, tt.want) {
+				t.Errorf("errors for %v, want %v", v.Errors(), tt.want)
+			}
+			if v.Valid() != (len(tt.want) == 0) {
+				t.Errorf("Valid() = %v with errors %v", v.Valid(), v.Errors())
+			}
+		})
+	}
+}
+
+func TestCast(t *testing.T) {
+	entry := func(billing int, character string, credit models.CreditType) models.CastEntry {
+		return models.CastEntry{ActorId: 1, Role: models.Role{Character: character, BillingOrder: billing, CreditType: credit}}
+	}
+
+	tests := []struct {
+		name string
+		cast []models.CastEntry
+		want []string
+	}{
+		{"empty", nil, nil},
+		{"billed", []models.CastEntry{entry(1, "Ripley", models.CreditLead), entry(2, "Dallas", "")}, nil},
+		{"unbilled repeat", []models.CastEntry{entry(0, "", ""), entry(0, "", models.CreditCameo)}, nil},
+		{"negative billing order", []models.CastEntry{entry(1, "", ""), entry(-1, "", "")}, []string{"cast[1].billing_order"}},
+		{"duplicate billing order", []models.CastEntry{entry(1, "", ""), entry(2, "", ""), entry(1, "", "")}, []string{"cast[2].billing_order"}},
+		{"long character", []models.CastEntry{entry(1, strings.Repeat("a", MaxCharacterLen+1), "")}, []string{"cast[0].character"}},
+		{"character at limit", []models.CastEntry{entry(1, strings.Repeat("ñ", MaxCharacterLen), "")}, nil},
+		{"unknown credit type", []models.CastEntry{entry(1, "", "star")}, []string{"cast[0].credit_type"}},
+		{"every error", []models.CastEntry{entry(-2, strings.Repeat("a", MaxCharacterLen+1), "star")},
+			[]string{"cast[0].character", "cast[0].billing_order", "cast[0].credit_type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			v.Cast(tt.cast)
+
+			if got := fields(&v); !slices.Equal(got, tt.want) {
+				t.Errorf("errors for %v, want %v", v.Errors(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMember(t *testing.T) {
+	tests := []struct {
+		name  string
+		field patch.Field[string]
+		given bool
+		want  []string
+	}{
+		{"absent", patch.Field[string]{}, false, nil},
+		{"value", patch.Field[string]{Set: true, Value: "Alien"}, true, nil},
+		{"null", patch.Field[string]{Set: true, Null: true}, false, []string{"title"}},
+		{"wrong type", patch.Field[string]{Set: true, Invalid: true}, false, []string{"title"}},
+	}
+
+	for _, tt := range tests {
+		var v Validator
+		value, given := Member(&v, "title", "a string", tt.field)
+
+		if given != tt.given || (given && value != tt.field.Value) {
+			t.Errorf("%s: Member = %q, %v", tt.name, value, given)
+		}
+		if got := fields(&v); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: errors for %v, want %v", tt.name, v.Errors(), tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var v Validator
+	if v.Errors() != nil {
+		t.Errorf("zero Validator has errors %v", v.Errors())
+	}
+
+	v.Check(false, "title", "must not be blank")
+	v.Check(true, "rating", "must be between 0 and 10")
+	v.Check(false, "birth", "must not be in the future")
+
+	if got, want := v.Errors().Error(), "validation failed: title: must not be blank; birth: must not be in the future"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}